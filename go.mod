@@ -2,7 +2,13 @@ module github.com/nzb3/obsidian-index
 
 go 1.25
 
-require github.com/spf13/cobra v1.10.1
+require (
+	github.com/bmatcuk/doublestar/v4 v4.10.0
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/sys v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
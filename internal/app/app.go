@@ -1,8 +1,11 @@
 package app
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/nzb3/obsidian-index/internal/indexator"
 )
@@ -12,7 +15,33 @@ type config interface {
 	IsVerbose() bool
 	IsDryRun() bool
 	IsBackup() bool
+	IsUpdate() bool
 	GetExcludeDirs() []string
+	GetMissingMarkers() string
+	GetTemplatePath() string
+	GetSortBy() string
+	GetSortOverrides() map[string]string
+	IsFoldersFirst() bool
+	IsGroup() bool
+	GetKinds() map[string]string
+	IsTitles() bool
+	IsObsidianExcludes() bool
+	IsRespectGitignore() bool
+	IsFull() bool
+	GetDebounce() time.Duration
+	GetJobs() int
+	IsDiff() bool
+	GetBackupDir() string
+	GetBackupKeep() int
+	GetBackupKeepDays() int
+	IsTransaction() bool
+	IsKeepGoing() bool
+	GetReport() string
+	GetReportFile() string
+	GetLogFormat() string
+	GetLogFile() string
+	GetLogLevel() string
+	IsQuiet() bool
 }
 
 type App struct {
@@ -31,23 +60,56 @@ func New(cfg config) *App {
 	return app
 }
 
+// initLogger sets up the default logger: text or JSON records on stderr or
+// in the log file, at the configured level. A log file that cannot be
+// opened is reported and stderr is used instead.
 func (app *App) initLogger() {
-	var level slog.Level
-	if app.cfg.IsVerbose() {
-		level = slog.LevelDebug
-	} else {
-		level = slog.LevelInfo
+	var out io.Writer = os.Stderr
+	var fileErr error
+	if logFile := app.cfg.GetLogFile(); logFile != "" {
+		// The file stays open for the life of the process
+		file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fileErr = err
+		} else {
+			out = file
+		}
 	}
 
 	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: true,
+		Level: app.logLevel(),
 	}
 
-	handler := slog.NewTextHandler(os.Stdout, opts)
+	var handler slog.Handler
+	if app.cfg.GetLogFormat() == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
 	logger := slog.New(handler)
 
 	slog.SetDefault(logger)
+	if fileErr != nil {
+		slog.Warn("failed to open log file, logging to stderr", "file", app.cfg.GetLogFile(), "error", fileErr)
+	}
+}
+
+// logLevel returns the minimum level logged: the configured level if any,
+// otherwise debug in verbose mode, error in quiet mode and info by default
+func (app *App) logLevel() slog.Level {
+	var level slog.Level
+	switch {
+	case app.cfg.GetLogLevel() != "":
+		// Validated with the configuration
+		level.UnmarshalText([]byte(app.cfg.GetLogLevel()))
+	case app.cfg.IsVerbose():
+		level = slog.LevelDebug
+	case app.cfg.IsQuiet():
+		level = slog.LevelError
+	default:
+		level = slog.LevelInfo
+	}
+	return level
 }
 
 func (app *App) initIndexator() *indexator.Indexator {
@@ -57,13 +119,135 @@ func (app *App) initIndexator() *indexator.Indexator {
 
 	app.indexator = indexator.NewIndexatorWithOptions(
 		app.cfg.GetVaultDir(),
-		app.cfg.IsDryRun(),
-		app.cfg.IsBackup(),
-		app.cfg.GetExcludeDirs(),
+		indexator.Options{
+			DryRun:         app.cfg.IsDryRun(),
+			Backup:         app.cfg.IsBackup(),
+			Update:         app.cfg.IsUpdate(),
+			ExcludeDirs:    app.cfg.GetExcludeDirs(),
+			MissingMarkers: app.cfg.GetMissingMarkers(),
+			TemplatePath:   app.cfg.GetTemplatePath(),
+			SortBy:         app.cfg.GetSortBy(),
+			SortOverrides:  app.cfg.GetSortOverrides(),
+			FoldersFirst:   app.cfg.IsFoldersFirst(),
+			Group:          app.cfg.IsGroup(),
+			Kinds:          app.cfg.GetKinds(),
+			Titles:         app.cfg.IsTitles(),
+
+			IgnoreObsidianExcludes: !app.cfg.IsObsidianExcludes(),
+			RespectGitignore:       app.cfg.IsRespectGitignore(),
+			Incremental:            true,
+			Full:                   app.cfg.IsFull(),
+			Debounce:               app.cfg.GetDebounce(),
+			Jobs:                   app.cfg.GetJobs(),
+			DiffWriter:             app.diffWriter(),
+			DiffColor:              useColor(app.output()),
+			BackupDir:              app.cfg.GetBackupDir(),
+			BackupKeep:             app.cfg.GetBackupKeep(),
+			BackupKeepDays:         app.cfg.GetBackupKeepDays(),
+			Journal:                true,
+			Transaction:            app.cfg.IsTransaction(),
+			KeepGoing:              app.cfg.IsKeepGoing(),
+		},
 	)
 	return app.indexator
 }
 
+// diffWriter returns where index diffs are printed, or nil if they are not
+func (app *App) diffWriter() io.Writer {
+	if !app.cfg.IsDiff() {
+		return nil
+	}
+	return app.output()
+}
+
+// output returns where diffs are printed: stderr when the run report is
+// written to stdout, so that the report can be parsed
+func (app *App) output() *os.File {
+	if app.ReportsToStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// ReportsToStdout reports whether the run report is written to stdout
+func (app *App) ReportsToStdout() bool {
+	return app.cfg.GetReport() != "" && app.cfg.GetReportFile() == ""
+}
+
+// useColor reports whether output to f should be colourised: f must be a
+// terminal and NO_COLOR must not be set
+func useColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func (app *App) Run() error {
 	return app.indexator.Start()
 }
+
+// Report describes the last run, directory by directory
+func (app *App) Report() *indexator.Report {
+	return app.indexator.Report()
+}
+
+// HandWrittenFiles returns the hand-written index files without markers the
+// last run left alone
+func (app *App) HandWrittenFiles() []string {
+	return app.indexator.HandWrittenFiles()
+}
+
+// Check compares the index files of the vault with what a run would
+// generate, without writing anything
+func (app *App) Check() ([]indexator.Change, error) {
+	return app.indexator.Check()
+}
+
+// Plan computes the changes a run would make to the index files of the
+// vault, without writing anything
+func (app *App) Plan() (*indexator.Plan, error) {
+	return app.indexator.Plan()
+}
+
+// Apply executes a plan made by Plan
+func (app *App) Apply(plan *indexator.Plan) error {
+	return app.indexator.Apply(plan)
+}
+
+// Clean removes the files generated by the tool from the vault and returns
+// their vault-relative paths
+func (app *App) Clean() ([]string, error) {
+	return app.indexator.Clean()
+}
+
+// BackupRuns lists the runs in the backup store, newest first
+func (app *App) BackupRuns() ([]indexator.BackupRun, error) {
+	return app.indexator.BackupRuns()
+}
+
+// Restore puts backed up files of a run back into the vault; an empty runID
+// selects the latest run
+func (app *App) Restore(runID string, paths []string) ([]string, error) {
+	return app.indexator.Restore(runID, paths)
+}
+
+// JournalRuns lists the runs that can be undone, newest first
+func (app *App) JournalRuns() ([]indexator.JournalRun, error) {
+	return app.indexator.JournalRuns()
+}
+
+// Undo reverts a run recorded in the journal; an empty runID selects the
+// latest run
+func (app *App) Undo(runID string, force bool) ([]string, error) {
+	return app.indexator.Undo(runID, force)
+}
+
+// Watch keeps the indexes of the vault up to date until ctx is cancelled
+func (app *App) Watch(ctx context.Context) error {
+	return app.indexator.Watch(ctx)
+}
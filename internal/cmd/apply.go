@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/nzb3/obsidian-index/internal/config"
+	"github.com/nzb3/obsidian-index/internal/indexator"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply PLAN",
+	Short: "Execute a plan file made by the plan command",
+	Long: `Execute exactly the changes recorded in a plan file made by plan.
+
+Before anything is written, every index file in the plan is compared with
+the SHA-256 recorded when the plan was made. If any of them changed, was
+created or was removed since then, apply refuses the whole plan, lists the
+conflicting files and leaves the vault untouched. Make a new plan in that
+case.
+
+The plan is applied to the vault it was made for unless --dir is given.`,
+	Example: `  obsidian-index apply plan.json
+  obsidian-index apply plan.json --backup
+  obsidian-index apply plan.json --dry-run --diff`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runApply,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	addWriteFlags(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	plan, err := readPlanFile(args[0])
+	if err != nil {
+		slog.Error("failed to read plan", "file", args[0], "error", err)
+		return err
+	}
+
+	var opts []config.Option
+	if !cmd.Flags().Changed("dir") {
+		opts = append(opts, config.WithVaultDir(plan.Vault))
+	}
+	cfg, err := loadConfig(cmd, opts...)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	if err := application.Apply(plan); err != nil {
+		slog.Error("apply failed", "vault", absPath, "error", err)
+		return fmt.Errorf("apply failed: %w", err)
+	}
+
+	if cfg.IsDryRun() {
+		fmt.Fprintf(out, "🔍 Dry run completed for vault: %s\n", absPath)
+	} else {
+		fmt.Fprintf(out, "✅ Applied %d index file changes to vault: %s\n", len(plan.Changes), absPath)
+	}
+	return nil
+}
+
+// readPlanFile reads a plan from the given file
+func readPlanFile(path string) (*indexator.Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan file: %w", err)
+	}
+	defer file.Close()
+
+	return indexator.ReadPlan(file)
+}
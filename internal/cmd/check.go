@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/nzb3/obsidian-index/internal/indexator"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the indexes of an Obsidian vault are up to date",
+	Long: `Compute every index in memory and compare it with the files on disk,
+without writing anything. Exits with status 3 and lists the differences
+when any index file is:
+
+  missing     a run would create it
+  stale       a run in update mode would rewrite it
+  extraneous  it carries the generated marker but a run would no longer
+              produce it, for example because its directory is now empty
+
+Hand-written folder notes are only reported when they contain a managed
+region that is out of date. Takes the same settings as init; the state of
+previous runs is ignored.`,
+	Example: `  obsidian-index check
+  obsidian-index check --dir ./docs --titles --group`,
+	SilenceUsage: true,
+	RunE:         runCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	addIndexFlags(checkCmd)
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	changes, err := application.Check()
+	if err != nil && !errors.Is(err, indexator.ErrStale) {
+		slog.Error("check failed", "vault", absPath, "error", err)
+		return fmt.Errorf("check failed: %w", err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "✅ All index files are up to date in vault: %s\n", absPath)
+		return nil
+	}
+
+	fmt.Fprintf(out, "❌ %d index files are out of date in vault: %s\n", len(changes), absPath)
+	for _, change := range changes {
+		fmt.Fprintf(out, "  %-11s %s\n", checkStatus(change.Action), change.Path)
+	}
+	return err
+}
+
+// checkStatus describes a pending change the way check reports it
+func checkStatus(action indexator.Action) string {
+	switch action {
+	case indexator.ActionCreate:
+		return "missing"
+	case indexator.ActionRemove:
+		return "extraneous"
+	default:
+		return "stale"
+	}
+}
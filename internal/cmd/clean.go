@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove every index file generated by obsidian-index from a vault",
+	Long: `Find and delete the files obsidian-index leaves in a vault:
+
+  - index files carrying the generated marker, found anywhere in the vault
+    and in the directories recorded in .obsidian-index/state.json
+  - .tmp files left over from interrupted writes of index files
+  - .backup_* files that older versions created next to index files
+  - the state of previous runs and files staged by interrupted runs; the
+    backup store and the journals are kept
+
+The removals are journaled like any run, so "obsidian-index undo" brings the
+files back.
+
+Hand-written folder notes are never deleted, even when they contain a managed
+region. Use --dry-run to list the files without removing them.`,
+	Example: `  obsidian-index clean --dry-run
+  obsidian-index clean --dir /path/to/obsidian/vault`,
+	RunE: runClean,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	addVaultFlags(cleanCmd)
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be removed without removing files")
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	removed, err := application.Clean()
+	if err != nil {
+		slog.Error("clean failed", "vault", absPath, "error", err)
+		return fmt.Errorf("clean failed: %w", err)
+	}
+
+	switch {
+	case len(removed) == 0:
+		fmt.Fprintf(out, "✅ Nothing to clean in vault: %s\n", absPath)
+	case cfg.IsDryRun():
+		fmt.Fprintf(out, "🔍 Would remove %d files from vault: %s\n", len(removed), absPath)
+	default:
+		fmt.Fprintf(out, "🧹 Removed %d files from vault: %s\n", len(removed), absPath)
+	}
+	for _, path := range removed {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	return nil
+}
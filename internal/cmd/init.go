@@ -1,22 +1,46 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
 
 	"github.com/nzb3/obsidian-index/internal/app"
 	"github.com/nzb3/obsidian-index/internal/config"
+	"github.com/nzb3/obsidian-index/internal/indexator"
 	"github.com/spf13/cobra"
 )
 
 var (
-	vaultDir    string
-	verbose     bool
-	dryRun      bool
-	backup      bool
-	excludeDirs []string
+	vaultDir       string
+	verbose        bool
+	dryRun         bool
+	backup         bool
+	update         bool
+	excludeDirs    []string
+	missingMarkers string
+	templatePath   string
+	sortBy         string
+	sortOverrides  map[string]string
+	foldersFirst   bool
+	group          bool
+	kinds          map[string]string
+	titles         bool
+
+	noObsidianExcludes bool
+	respectGitignore   bool
+	full               bool
+	jobs               int
+	showDiff           bool
+	backupDir          string
+	backupKeep         int
+	backupKeepDays     int
+	noTransaction      bool
+	keepGoing          bool
+	reportFormat       string
+	reportFile         string
 )
 
 var initCmd = &cobra.Command{
@@ -27,73 +51,293 @@ This command will recursively index all directories starting from the
 deepest level (leaves) and working up to the root directory.
 
 Each directory will get an index file named after the directory containing
-markdown links to all files and subdirectories within it.`,
+markdown links to all files and subdirectories within it.
+
+Existing index files are left untouched unless --update is given; even then
+only files generated by obsidian-index, or the managed regions of folder
+notes, are rewritten. Only directories that changed since the previous run
+are regenerated, and the changes are written together once every directory
+was indexed. See the README for settings files, templates, sorting and
+reports.`,
 	Example: `  obsidian-index init
   obsidian-index init --dir /path/to/obsidian/vault
-  obsidian-index init -d ~/Documents/MyVault --verbose`,
-	RunE: runInit,
+  obsidian-index init -d ~/Documents/MyVault --verbose
+  obsidian-index init --update --backup
+  obsidian-index init --update --dry-run --diff
+  obsidian-index init --update --full
+  obsidian-index init --update --keep-going --report json
+  obsidian-index init --sort natural --folders-first --titles --group
+  obsidian-index init --update --template ~/.config/obsidian-index/index.tmpl`,
+	SilenceUsage: true,
+	RunE:         runInit,
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
 
-	initCmd.Flags().StringVarP(&vaultDir, "dir", "d", "", "path to the Obsidian vault directory (default: current directory)")
+	addIndexFlags(initCmd)
+	initCmd.Flags().BoolVarP(&update, "update", "u", false, "regenerate index files previously created by obsidian-index")
+	initCmd.Flags().StringVar(&reportFormat, "report", "", "write a report of every directory in the given format: json")
+	initCmd.Flags().StringVar(&reportFile, "report-file", "", "file to write the report to (default: stdout)")
+}
 
-	initCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
-	initCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be done without creating files")
-	initCmd.Flags().BoolVar(&backup, "backup", false, "create backup of existing index files")
-	initCmd.Flags().StringSliceVar(&excludeDirs, "exclude", []string{}, "directories to exclude from indexing")
+// addIndexFlags registers the flags shared by the commands that index a vault
+func addIndexFlags(cmd *cobra.Command) {
+	addWriteFlags(cmd)
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "number of directories to index concurrently")
+	cmd.Flags().BoolVar(&full, "full", false, "rebuild every index instead of only directories that changed since the last run")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "keep indexing the other directories when one fails")
+	cmd.Flags().StringVar(&missingMarkers, "missing-markers", indexator.MissingMarkersSkip, "policy for existing index files without markers: skip, append or prepend")
+	cmd.Flags().StringVar(&templatePath, "template", "", "path to a Go text/template file used to render index files")
+	cmd.Flags().StringVar(&sortBy, "sort", indexator.SortName, "sort strategy for index entries: name, natural, nocase, mtime, ctime or order")
+	cmd.Flags().StringToStringVar(&sortOverrides, "sort-dir", map[string]string{}, "per-directory sort strategy as DIR=STRATEGY (inherited by subdirectories)")
+	cmd.Flags().BoolVar(&foldersFirst, "folders-first", false, "list subfolders before files")
+	cmd.Flags().BoolVar(&group, "group", false, "group index entries into sections by kind")
+	cmd.Flags().StringToStringVar(&kinds, "kind", map[string]string{}, "map a file extension to a kind as EXT=KIND (notes, canvases or attachments)")
+	cmd.Flags().BoolVar(&titles, "titles", false, "use note titles from frontmatter or first heading as link aliases")
+	cmd.Flags().StringSliceVar(&excludeDirs, "exclude", []string{}, "gitignore-style patterns of files and directories to exclude from indexing")
+	cmd.Flags().BoolVar(&noObsidianExcludes, "no-obsidian-excludes", false, "ignore the \"Excluded files\" setting in .obsidian/app.json")
+	cmd.Flags().BoolVar(&respectGitignore, "respect-gitignore", false, "exclude paths ignored by .gitignore files in the vault")
 }
 
-func runInit(cmd *cobra.Command, args []string) error {
-	dir := vaultDir
-	if dir == "" {
-		var err error
-		dir, err = os.Getwd()
-		if err != nil {
-			slog.Error("failed to get current working directory", "error", err)
-			return fmt.Errorf("failed to get current working directory: %w", err)
-		}
-	}
+// addWriteFlags registers the flags shared by the commands that write index
+// files
+func addWriteFlags(cmd *cobra.Command) {
+	addVaultFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be done without creating files")
+	cmd.Flags().BoolVar(&showDiff, "diff", false, "print a unified diff of every index file change")
+	cmd.Flags().BoolVar(&backup, "backup", false, "create backup of existing index files")
+	addBackupDirFlag(cmd)
+	cmd.Flags().IntVar(&backupKeep, "backup-keep", 0, "number of backup runs to keep (0 keeps all)")
+	cmd.Flags().IntVar(&backupKeepDays, "backup-keep-days", 0, "number of days to keep backup runs (0 keeps them regardless of age)")
+	cmd.Flags().BoolVar(&noTransaction, "no-transaction", false, "write each index file as soon as it is generated instead of staging them")
+}
 
-	absPath, err := filepath.Abs(dir)
-	if err != nil {
-		slog.Error("failed to get absolute path", "directory", dir, "error", err)
-		return fmt.Errorf("failed to get absolute path: %w", err)
-	}
+// addBackupDirFlag registers the flag selecting the backup store
+func addBackupDirFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&backupDir, "backup-dir", "", "directory to store backups in (default: .obsidian-index/backups in the vault)")
+}
 
-	cfg := config.NewWithAllOptions(absPath, verbose, dryRun, backup, excludeDirs)
+// addVaultFlags registers the flags shared by every command that works on a
+// vault
+func addVaultFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&vaultDir, "dir", "d", "", "path to the Obsidian vault directory (default: current directory)")
+}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		slog.Error("configuration validation failed", "error", err)
-		return fmt.Errorf("configuration validation failed: %w", err)
+func runInit(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
 	}
+	absPath := cfg.GetVaultDir()
 
-	if verbose {
-		fmt.Printf("Starting indexation of vault: %s\n", absPath)
-		if dryRun {
-			fmt.Println("🔍 DRY RUN MODE - No files will be created")
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	if cfg.IsVerbose() {
+		fmt.Fprintf(out, "Starting indexation of vault: %s\n", absPath)
+		if cfg.IsDryRun() {
+			fmt.Fprintln(out, "🔍 DRY RUN MODE - No files will be created")
+		}
+		if cfg.IsUpdate() {
+			fmt.Fprintln(out, "🔄 UPDATE MODE - Generated index files will be regenerated")
 		}
-		if backup {
-			fmt.Println("💾 BACKUP MODE - Existing index files will be backed up")
+		if cfg.IsBackup() {
+			fmt.Fprintln(out, "💾 BACKUP MODE - Existing index files will be backed up")
 		}
-		if len(excludeDirs) > 0 {
-			fmt.Printf("🚫 Excluding directories: %v\n", excludeDirs)
+		if len(cfg.GetExcludeDirs()) > 0 {
+			fmt.Fprintf(out, "🚫 Excluding directories: %v\n", cfg.GetExcludeDirs())
+		}
+		if cfg.IsRespectGitignore() {
+			fmt.Fprintln(out, "🙈 Excluding paths ignored by .gitignore")
 		}
 	}
 
-	application := app.New(cfg)
+	runErr := application.Run()
+	if cfg.GetReport() != "" {
+		if err := writeReport(application.Report(), cfg.GetReportFile()); err != nil {
+			slog.Error("failed to write report", "error", err)
+			return errors.Join(runErr, err)
+		}
+	}
 
-	if err := application.Run(); err != nil {
-		slog.Error("indexation failed", "vault", absPath, "error", err)
-		return fmt.Errorf("indexation failed: %w", err)
+	if runErr != nil {
+		failed := indexator.DirErrors(runErr)
+		if failed == nil {
+			slog.Error("indexation failed", "vault", absPath, "error", runErr)
+			return fmt.Errorf("indexation failed: %w", runErr)
+		}
+
+		fmt.Fprintf(out, "⚠️  Indexed vault with %d failed directories: %s\n", len(failed), absPath)
+		for _, dirErr := range failed {
+			fmt.Fprintf(out, "  %s: %v\n", dirErr.Dir, dirErr.Err)
+		}
+		return fmt.Errorf("%w: %d directories failed", indexator.ErrPartialFailure, len(failed))
 	}
 
-	if dryRun {
-		fmt.Printf("🔍 Dry run completed for vault: %s\n", absPath)
+	if cfg.IsDryRun() {
+		fmt.Fprintf(out, "🔍 Dry run completed for vault: %s\n", absPath)
 	} else {
-		fmt.Printf("✅ Successfully indexed vault: %s\n", absPath)
+		fmt.Fprintf(out, "✅ Successfully indexed vault: %s\n", absPath)
+	}
+	if handWritten := application.HandWrittenFiles(); len(handWritten) > 0 {
+		fmt.Fprintf(out, "⚠️  Skipped %d hand-written index files without markers (see --missing-markers)\n", len(handWritten))
+		for _, path := range handWritten {
+			fmt.Fprintf(out, "  %s\n", path)
+		}
 	}
 	return nil
 }
+
+// statusWriter returns where the status lines of a command are printed:
+// nowhere in quiet mode, stderr when the run report is written to stdout,
+// stdout otherwise
+func statusWriter(cfg *config.Config, application *app.App) io.Writer {
+	switch {
+	case cfg.IsQuiet():
+		return io.Discard
+	case application.ReportsToStdout():
+		return os.Stderr
+	default:
+		return os.Stdout
+	}
+}
+
+// writeReport writes the run report to a file, or to stdout if path is
+// empty. Nothing is written if the run did not start.
+func writeReport(report *indexator.Report, path string) error {
+	if report == nil {
+		return nil
+	}
+	if path == "" {
+		return indexator.WriteReport(os.Stdout, report)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	if err := indexator.WriteReport(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadConfig loads and validates the configuration of a command. The given
+// options are applied last and take precedence over flags set on the command
+// line, which take precedence over configuration files and the environment.
+func loadConfig(cmd *cobra.Command, opts ...config.Option) (*config.Config, error) {
+	cfg, err := config.Load(config.LoadOptions{
+		ConfigFile: configFile,
+		Overrides:  append(flagOverrides(cmd), opts...),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	return cfg, nil
+}
+
+// flagOverrides returns config options for the flags set on the command
+// line, which take precedence over configuration files and the environment
+func flagOverrides(cmd *cobra.Command) []config.Option {
+	flags := cmd.Flags()
+
+	var opts []config.Option
+	if flags.Changed("dir") {
+		opts = append(opts, config.WithVaultDir(vaultDir))
+	}
+	if flags.Changed("verbose") {
+		opts = append(opts, config.WithVerbose(verbose))
+	}
+	if flags.Changed("quiet") {
+		opts = append(opts, config.WithQuiet(quiet))
+	}
+	if flags.Changed("log-format") {
+		opts = append(opts, config.WithLogFormat(logFormat))
+	}
+	if flags.Changed("log-file") {
+		opts = append(opts, config.WithLogFile(logFile))
+	}
+	if flags.Changed("log-level") {
+		opts = append(opts, config.WithLogLevel(logLevel))
+	}
+	if flags.Changed("dry-run") {
+		opts = append(opts, config.WithDryRun(dryRun))
+	}
+	if flags.Changed("backup") {
+		opts = append(opts, config.WithBackup(backup))
+	}
+	if flags.Changed("update") {
+		opts = append(opts, config.WithUpdate(update))
+	}
+	if flags.Changed("exclude") {
+		opts = append(opts, config.WithExcludeDirs(excludeDirs))
+	}
+	if flags.Changed("missing-markers") {
+		opts = append(opts, config.WithMissingMarkers(missingMarkers))
+	}
+	if flags.Changed("template") {
+		opts = append(opts, config.WithTemplatePath(templatePath))
+	}
+	if flags.Changed("sort") {
+		opts = append(opts, config.WithSortBy(sortBy))
+	}
+	if flags.Changed("sort-dir") {
+		opts = append(opts, config.WithSortOverrides(sortOverrides))
+	}
+	if flags.Changed("folders-first") {
+		opts = append(opts, config.WithFoldersFirst(foldersFirst))
+	}
+	if flags.Changed("group") {
+		opts = append(opts, config.WithGroup(group))
+	}
+	if flags.Changed("kind") {
+		opts = append(opts, config.WithKinds(kinds))
+	}
+	if flags.Changed("titles") {
+		opts = append(opts, config.WithTitles(titles))
+	}
+	if flags.Changed("no-obsidian-excludes") {
+		opts = append(opts, config.WithObsidianExcludes(!noObsidianExcludes))
+	}
+	if flags.Changed("diff") {
+		opts = append(opts, config.WithDiff(showDiff))
+	}
+	if flags.Changed("jobs") {
+		opts = append(opts, config.WithJobs(jobs))
+	}
+	if flags.Changed("full") {
+		opts = append(opts, config.WithFull(full))
+	}
+	if flags.Changed("respect-gitignore") {
+		opts = append(opts, config.WithRespectGitignore(respectGitignore))
+	}
+	if flags.Changed("backup-dir") {
+		opts = append(opts, config.WithBackupDir(backupDir))
+	}
+	if flags.Changed("backup-keep") {
+		opts = append(opts, config.WithBackupKeep(backupKeep))
+	}
+	if flags.Changed("backup-keep-days") {
+		opts = append(opts, config.WithBackupKeepDays(backupKeepDays))
+	}
+	if flags.Changed("report") {
+		opts = append(opts, config.WithReport(reportFormat))
+	}
+	if flags.Changed("report-file") {
+		opts = append(opts, config.WithReportFile(reportFile))
+	}
+	if flags.Changed("keep-going") {
+		opts = append(opts, config.WithKeepGoing(keepGoing))
+	}
+	if flags.Changed("no-transaction") {
+		opts = append(opts, config.WithTransaction(!noTransaction))
+	}
+	return opts
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/nzb3/obsidian-index/internal/indexator"
+	"github.com/spf13/cobra"
+)
+
+var planOutput string
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Record the changes to the indexes of an Obsidian vault in a plan file",
+	Long: `Compute every change a run would make to the index files of a vault and
+write it to a plan file, without touching the vault. Each change records the
+action (create, update or remove), the path of the index file, the SHA-256
+of the content it expects on disk and the new content.
+
+Execute the plan with apply. Takes the same settings as init. Unlike a dry
+run the state of previous runs is ignored, so every index is compared. In
+update mode generated index files that a run would no longer produce are
+removed, as check reports them.`,
+	Example: `  obsidian-index plan -o plan.json
+  obsidian-index plan --update --diff -o plan.json
+  obsidian-index apply plan.json`,
+	RunE: runPlanCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	addIndexFlags(planCmd)
+	planCmd.Flags().BoolVarP(&update, "update", "u", false, "regenerate index files previously created by obsidian-index")
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "", "file to write the plan to")
+	planCmd.MarkFlagRequired("output")
+}
+
+func runPlanCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	plan, err := application.Plan()
+	if err != nil {
+		slog.Error("planning failed", "vault", absPath, "error", err)
+		return fmt.Errorf("planning failed: %w", err)
+	}
+
+	if err := writePlanFile(planOutput, plan); err != nil {
+		slog.Error("failed to write plan", "file", planOutput, "error", err)
+		return err
+	}
+
+	if len(plan.Changes) == 0 {
+		fmt.Fprintf(out, "✅ All index files are up to date in vault: %s\n", absPath)
+	} else {
+		fmt.Fprintf(out, "📝 %d index file changes planned in vault: %s\n", len(plan.Changes), absPath)
+		for _, change := range plan.Changes {
+			fmt.Fprintf(out, "  %-7s %s\n", change.Action, change.Path)
+		}
+	}
+	fmt.Fprintf(out, "💾 Plan written to %s\n", planOutput)
+	return nil
+}
+
+// writePlanFile writes a plan to the given file
+func writePlanFile(path string, plan *indexator.Plan) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create plan file: %w", err)
+	}
+	if err := indexator.WritePlan(file, plan); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
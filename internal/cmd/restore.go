@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreRun  string
+	listBackups bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [PATH...]",
+	Short: "Restore index files from the backup store",
+	Long: `Put backed up index files back into the vault, overwriting their current
+versions. Runs with --backup move the previous version of every rewritten
+index into the backup store, one directory per run.
+
+Without --run the latest run is restored. Paths are relative to the vault
+and restrict the restore to the given files or directories. The backups are
+kept, so a restore can be repeated. Use --list to see the runs in the store.`,
+	Example: `  obsidian-index restore --list
+  obsidian-index restore
+  obsidian-index restore --run 20240101_120000 Projects/Projects.md
+  obsidian-index restore --dry-run Projects`,
+	SilenceUsage: true,
+	RunE:         runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	addVaultFlags(restoreCmd)
+	addBackupDirFlag(restoreCmd)
+	restoreCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be restored without writing files")
+	restoreCmd.Flags().StringVar(&restoreRun, "run", "", "ID of the backup run to restore (default: the latest run)")
+	restoreCmd.Flags().BoolVar(&listBackups, "list", false, "list the runs in the backup store instead of restoring")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	if listBackups {
+		runs, err := application.BackupRuns()
+		if err != nil {
+			slog.Error("failed to list backups", "vault", absPath, "error", err)
+			return fmt.Errorf("failed to list backups: %w", err)
+		}
+		if len(runs) == 0 {
+			fmt.Fprintf(out, "📭 No backups for vault: %s\n", absPath)
+			return nil
+		}
+		for _, run := range runs {
+			fmt.Printf("%s  %s  %d files\n", run.ID, run.Time.Format("2006-01-02 15:04:05"), len(run.Files))
+		}
+		return nil
+	}
+
+	restored, err := application.Restore(restoreRun, args)
+	if err != nil {
+		slog.Error("restore failed", "vault", absPath, "error", err)
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	if cfg.IsDryRun() {
+		fmt.Fprintf(out, "🔍 Would restore %d files in vault: %s\n", len(restored), absPath)
+	} else {
+		fmt.Fprintf(out, "♻️ Restored %d files in vault: %s\n", len(restored), absPath)
+	}
+	for _, path := range restored {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	return nil
+}
@@ -1,44 +1,96 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"log/slog"
 	"os"
 
+	"github.com/nzb3/obsidian-index/internal/config"
+	"github.com/nzb3/obsidian-index/internal/indexator"
 	"github.com/nzb3/obsidian-index/internal/version"
 	"github.com/spf13/cobra"
 )
 
+var (
+	configFile string
+	quiet      bool
+	logFormat  string
+	logFile    string
+	logLevel   string
+)
+
+// Exit codes of the command, documented in the help of the root command
+const (
+	exitFailure        = 1
+	exitPartialFailure = 2
+	exitStale          = 3
+	exitConflict       = 4
+	exitInvalidVault   = 5
+	exitPermission     = 6
+)
+
 var rootCmd = &cobra.Command{
-	Use:   "obsidian-index",
-	Short: "A CLI tool for indexing Obsidian vaults",
+	Use:     "obsidian-index",
+	Version: version.String(),
+	Short:   "A CLI tool for indexing Obsidian vaults",
+	// Errors are printed once by Execute
+	SilenceErrors: true,
 	Long: `obsidian-index is a powerful CLI tool that creates comprehensive
 indexes for your Obsidian vault by generating markdown files with links
-to all entries in each directory, processing from leaves to root.`,
-}
+to all entries in each directory, processing from leaves to root.
 
-func Execute() {
-	rootCmd.ParseFlags(os.Args[1:])
+Logs are written to stderr, or appended to --log-file, as text or as JSON
+with --log-format json. --log-level sets the minimum level logged; --verbose
+lowers it to debug and --quiet raises it to error and omits status lines.
 
-	versionFlag, err := rootCmd.Flags().GetBool("version")
-	if err != nil {
-		slog.Error("failed to parse version flag", "error", err)
-		fmt.Fprintf(os.Stderr, "Error parsing version flag: %v\n", err)
-		os.Exit(1)
-	}
+Exit status:
+  0  success, including when there was nothing to do
+  1  any other error
+  2  some directories could not be indexed (--keep-going)
+  3  index files are out of date (check)
+  4  index files were edited by hand since they were planned or written
+     (apply, undo)
+  5  the vault directory is missing or unusable
+  6  permission denied`,
+}
 
-	if versionFlag {
-		fmt.Println(version.String())
-		return
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		slog.Error("error executing command", "error", err)
-		fmt.Fprintf(os.Stderr, "Error executing command: %v\n", err)
-		os.Exit(1)
+// exitCode returns the exit status for an error returned by a command. A
+// partial failure is checked first, since the failures it joins may be
+// permission errors.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, indexator.ErrPartialFailure):
+		return exitPartialFailure
+	case errors.Is(err, indexator.ErrStale):
+		return exitStale
+	case errors.Is(err, indexator.ErrConflict):
+		return exitConflict
+	case errors.Is(err, config.ErrInvalidVault):
+		return exitInvalidVault
+	case errors.Is(err, indexator.ErrPermission):
+		return exitPermission
+	default:
+		return exitFailure
 	}
 }
 
 func init() {
-	rootCmd.Flags().BoolP("version", "v", false, "Print version information")
+	// --version is added by cobra; -v is taken by --verbose
+	rootCmd.SetVersionTemplate("{{.Version}}\n")
+
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&configFile, "config", "", "path to a configuration file (default: obsidian-index.yaml in the vault, then $XDG_CONFIG_HOME/obsidian-index/config.yaml)")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "enable verbose output and debug logs")
+	flags.BoolVarP(&quiet, "quiet", "q", false, "only log errors and omit status lines")
+	flags.StringVar(&logFormat, "log-format", config.LogFormatText, "format of log records: text or json")
+	flags.StringVar(&logFile, "log-file", "", "file to append logs to (default: stderr)")
+	flags.StringVar(&logLevel, "log-level", "", "minimum level logged: debug, info, warn or error (default: info)")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
 }
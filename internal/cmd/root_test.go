@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/nzb3/obsidian-index/internal/config"
+	"github.com/nzb3/obsidian-index/internal/indexator"
+)
+
+func TestExitCode(t *testing.T) {
+	permissionErr := &fs.PathError{Op: "open", Path: "A/A.md", Err: fs.ErrPermission}
+	partialErr := fmt.Errorf("%w: %w", indexator.ErrPartialFailure,
+		errors.Join(&indexator.DirError{Dir: "A", Err: permissionErr}))
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "other error", err: errors.New("boom"), want: exitFailure},
+		{name: "partial failure", err: partialErr, want: exitPartialFailure},
+		{name: "stale", err: fmt.Errorf("check failed: %w", indexator.ErrStale), want: exitStale},
+		{name: "conflict", err: fmt.Errorf("undo failed: %w", indexator.ErrConflict), want: exitConflict},
+		{name: "invalid vault", err: fmt.Errorf("configuration validation failed: %w", config.ErrInvalidVault), want: exitInvalidVault},
+		{name: "permission denied", err: fmt.Errorf("indexation failed: %w", permissionErr), want: exitPermission},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoRun     string
+	forceUndo   bool
+	listJournal bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the most recent run, or a named one",
+	Long: `Revert the files written, backed up or removed by a run, using the
+journal kept in .obsidian-index/journal. Every file the run touched is put
+back exactly as it was before the run, and files it created are removed.
+
+Without --run the most recent run is reverted; undoing again reverts the run
+before it. The journals of the last 20 runs are kept.
+
+Before anything is written every file is compared with the hash recorded at
+the end of the run. If any of them was edited since, undo refuses the whole
+run and lists the conflicting files, unless --force is given.`,
+	Example: `  obsidian-index undo
+  obsidian-index undo --list
+  obsidian-index undo --run 20240101_120000 --dry-run
+  obsidian-index undo --force`,
+	SilenceUsage: true,
+	RunE:         runUndo,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	addVaultFlags(undoCmd)
+	undoCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be reverted without writing files")
+	undoCmd.Flags().StringVar(&undoRun, "run", "", "ID of the run to revert (default: the most recent run)")
+	undoCmd.Flags().BoolVar(&forceUndo, "force", false, "revert files even if they were edited since the run")
+	undoCmd.Flags().BoolVar(&listJournal, "list", false, "list the runs that can be undone instead of reverting")
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	if listJournal {
+		runs, err := application.JournalRuns()
+		if err != nil {
+			slog.Error("failed to list runs", "vault", absPath, "error", err)
+			return fmt.Errorf("failed to list runs: %w", err)
+		}
+		if len(runs) == 0 {
+			fmt.Fprintf(out, "📭 No runs to undo in vault: %s\n", absPath)
+			return nil
+		}
+		for _, run := range runs {
+			fmt.Printf("%s  %s  %d files\n", run.ID, run.Time.Format("2006-01-02 15:04:05"), len(run.Files))
+		}
+		return nil
+	}
+
+	reverted, err := application.Undo(undoRun, forceUndo)
+	if err != nil {
+		slog.Error("undo failed", "vault", absPath, "error", err)
+		return fmt.Errorf("undo failed: %w", err)
+	}
+
+	if cfg.IsDryRun() {
+		fmt.Fprintf(out, "🔍 Would revert %d files in vault: %s\n", len(reverted), absPath)
+	} else {
+		fmt.Fprintf(out, "⏪ Reverted %d files in vault: %s\n", len(reverted), absPath)
+	}
+	for _, path := range reverted {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	return nil
+}
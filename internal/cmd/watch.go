@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nzb3/obsidian-index/internal/app"
+	"github.com/nzb3/obsidian-index/internal/config"
+	"github.com/nzb3/obsidian-index/internal/indexator"
+	"github.com/spf13/cobra"
+)
+
+var debounce time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the indexes of an Obsidian vault up to date",
+	Long: `Index an Obsidian vault and keep its indexes up to date as files are
+created, changed, renamed or deleted.
+
+Changes are collected until the vault has been quiet for --debounce, so a
+burst of edits, for example from Obsidian Sync, produces a single rewrite.
+Only the affected directories and their ancestors are regenerated. Changes to
+settings files trigger a rescan of the whole vault.
+
+Watch mode implies --update: index files previously generated by
+obsidian-index are rewritten, hand-written folder notes are left alone.
+
+Takes the same settings as init. Stops cleanly on SIGINT or SIGTERM.`,
+	Example: `  obsidian-index watch
+  obsidian-index watch --dir ~/Documents/MyVault --debounce 5s
+  obsidian-index watch --titles --group`,
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addIndexFlags(watchCmd)
+	watchCmd.Flags().DurationVar(&debounce, "debounce", indexator.DefaultDebounce, "how long to wait for the vault to settle before updating indexes")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	opts := []config.Option{config.WithUpdate(true)}
+	if cmd.Flags().Changed("debounce") {
+		opts = append(opts, config.WithDebounce(debounce))
+	}
+
+	cfg, err := loadConfig(cmd, opts...)
+	if err != nil {
+		return err
+	}
+	absPath := cfg.GetVaultDir()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application := app.New(cfg)
+	out := statusWriter(cfg, application)
+
+	fmt.Fprintf(out, "👀 Watching vault: %s (press Ctrl+C to stop)\n", absPath)
+	if cfg.IsDryRun() {
+		fmt.Fprintln(out, "🔍 DRY RUN MODE - No files will be created")
+	}
+
+	if err := application.Watch(ctx); err != nil {
+		slog.Error("watch failed", "vault", absPath, "error", err)
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	fmt.Fprintf(out, "👋 Stopped watching vault: %s\n", absPath)
+	return nil
+}
@@ -2,47 +2,129 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nzb3/obsidian-index/internal/ignore"
+	"github.com/nzb3/obsidian-index/internal/indexator"
 )
 
+// ErrInvalidVault is returned by Validate when the vault directory is missing
+// or cannot be used
+var ErrInvalidVault = errors.New("invalid vault")
+
 type Config struct {
 	vaultDir    string
 	verbose     bool
 	dryRun      bool
 	backup      bool
+	update      bool
 	excludeDirs []string
+	// missingMarkers is the policy for existing index files without markers
+	missingMarkers string
+	// templatePath points to a text/template file used to render index files
+	templatePath string
+	sortBy       string
+	// sortOverrides maps vault-relative directories to sort strategies
+	sortOverrides map[string]string
+	foldersFirst  bool
+	group         bool
+	// kinds maps file extensions to entry kinds used for grouping
+	kinds map[string]string
+	// titles uses note titles as link aliases
+	titles bool
+	// obsidianExcludes honours the "Excluded files" setting of Obsidian
+	obsidianExcludes bool
+	// respectGitignore excludes paths ignored by .gitignore files
+	respectGitignore bool
+	// full ignores the state of the previous run and rebuilds every index
+	full bool
+	// debounce is how long watch mode waits for the vault to settle
+	debounce time.Duration
+	// jobs is the number of directories indexed concurrently
+	jobs int
+	// diff prints a unified diff of every index change
+	diff bool
+	// backupDir is where backups are stored, by default in the state
+	// directory of the vault
+	backupDir string
+	// backupKeep is the number of backup runs kept, or 0 to keep all
+	backupKeep int
+	// backupKeepDays is how many days backup runs are kept, or 0 to keep
+	// them regardless of age
+	backupKeepDays int
+	// transaction stages the changes of a run and only writes them once
+	// every directory was indexed
+	transaction bool
+	// keepGoing carries on indexing when a directory fails
+	keepGoing bool
+	// report is the format of the run report, empty for none
+	report string
+	// reportFile is where the run report is written, empty for stdout
+	reportFile string
+	// logFormat is the format of log records, text or json
+	logFormat string
+	// logFile is where logs are written, empty for stderr
+	logFile string
+	// logLevel is the minimum level logged; empty derives it from verbose
+	// and quiet
+	logLevel string
+	// quiet only logs errors and drops status lines
+	quiet bool
 }
 
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Run report formats
+const (
+	ReportJSON = "json"
+)
+
 func New() *Config {
 	return &Config{
-		vaultDir:    "",
-		verbose:     false,
-		dryRun:      false,
-		backup:      false,
-		excludeDirs: []string{},
+		vaultDir:         "",
+		verbose:          false,
+		dryRun:           false,
+		backup:           false,
+		excludeDirs:      []string{},
+		missingMarkers:   indexator.MissingMarkersSkip,
+		sortBy:           indexator.SortName,
+		obsidianExcludes: true,
+		transaction:      true,
+		debounce:         indexator.DefaultDebounce,
+		logFormat:        LogFormatText,
+		jobs:             1,
 	}
 }
 
 func NewWithOptions(vaultDir string, verbose bool) *Config {
-	return &Config{
-		vaultDir:    vaultDir,
-		verbose:     verbose,
-		dryRun:      false,
-		backup:      false,
-		excludeDirs: []string{},
-	}
+	cfg := New()
+	cfg.vaultDir = vaultDir
+	cfg.verbose = verbose
+	return cfg
 }
 
-func NewWithAllOptions(vaultDir string, verbose, dryRun, backup bool, excludeDirs []string) *Config {
-	return &Config{
-		vaultDir:    vaultDir,
-		verbose:     verbose,
-		dryRun:      dryRun,
-		backup:      backup,
-		excludeDirs: excludeDirs,
+func NewWithAllOptions(vaultDir string, verbose, dryRun, backup bool, excludeDirs []string, opts ...Option) *Config {
+	cfg := NewWithOptions(vaultDir, verbose)
+	cfg.dryRun = dryRun
+	cfg.backup = backup
+	cfg.excludeDirs = excludeDirs
+
+	for _, opt := range opts {
+		opt(cfg)
 	}
+
+	return cfg
 }
 
 func (c *Config) GetVaultDir() string {
@@ -61,38 +143,225 @@ func (c *Config) IsBackup() bool {
 	return c.backup
 }
 
+func (c *Config) IsUpdate() bool {
+	return c.update
+}
+
 func (c *Config) GetExcludeDirs() []string {
 	return c.excludeDirs
 }
 
-// Validate checks if the configuration is valid
+func (c *Config) GetMissingMarkers() string {
+	return c.missingMarkers
+}
+
+func (c *Config) GetTemplatePath() string {
+	return c.templatePath
+}
+
+func (c *Config) GetSortBy() string {
+	return c.sortBy
+}
+
+func (c *Config) GetSortOverrides() map[string]string {
+	return c.sortOverrides
+}
+
+func (c *Config) IsFoldersFirst() bool {
+	return c.foldersFirst
+}
+
+func (c *Config) IsGroup() bool {
+	return c.group
+}
+
+func (c *Config) GetKinds() map[string]string {
+	return c.kinds
+}
+
+func (c *Config) IsTitles() bool {
+	return c.titles
+}
+
+func (c *Config) IsObsidianExcludes() bool {
+	return c.obsidianExcludes
+}
+
+func (c *Config) IsRespectGitignore() bool {
+	return c.respectGitignore
+}
+
+func (c *Config) IsFull() bool {
+	return c.full
+}
+
+func (c *Config) GetDebounce() time.Duration {
+	return c.debounce
+}
+
+func (c *Config) GetJobs() int {
+	return c.jobs
+}
+
+func (c *Config) IsDiff() bool {
+	return c.diff
+}
+
+func (c *Config) GetBackupDir() string {
+	return c.backupDir
+}
+
+func (c *Config) GetBackupKeep() int {
+	return c.backupKeep
+}
+
+func (c *Config) GetBackupKeepDays() int {
+	return c.backupKeepDays
+}
+
+func (c *Config) IsTransaction() bool {
+	return c.transaction
+}
+
+func (c *Config) IsKeepGoing() bool {
+	return c.keepGoing
+}
+
+func (c *Config) GetReport() string {
+	return c.report
+}
+
+func (c *Config) GetReportFile() string {
+	return c.reportFile
+}
+
+func (c *Config) GetLogFormat() string {
+	return c.logFormat
+}
+
+func (c *Config) GetLogFile() string {
+	return c.logFile
+}
+
+func (c *Config) GetLogLevel() string {
+	return c.logLevel
+}
+
+func (c *Config) IsQuiet() bool {
+	return c.quiet
+}
+
+// Validate checks if the configuration is valid. Problems with the vault
+// directory wrap ErrInvalidVault.
 func (c *Config) Validate() error {
 	if c.vaultDir == "" {
-		return errors.New("vault directory is required")
+		return fmt.Errorf("%w: vault directory is required", ErrInvalidVault)
 	}
 
 	// Check if vault directory exists and is accessible
 	info, err := os.Stat(c.vaultDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return errors.New("vault directory does not exist: " + c.vaultDir)
+			return fmt.Errorf("%w: vault directory does not exist: %s", ErrInvalidVault, c.vaultDir)
 		}
-		return errors.New("cannot access vault directory: " + err.Error())
+		return fmt.Errorf("%w: cannot access vault directory: %w", ErrInvalidVault, err)
 	}
 
 	if !info.IsDir() {
-		return errors.New("vault path is not a directory: " + c.vaultDir)
+		return fmt.Errorf("%w: vault path is not a directory: %s", ErrInvalidVault, c.vaultDir)
 	}
 
 	// Check if vault directory is absolute path
 	if !filepath.IsAbs(c.vaultDir) {
-		return errors.New("vault directory must be an absolute path: " + c.vaultDir)
+		return fmt.Errorf("%w: vault directory must be an absolute path: %s", ErrInvalidVault, c.vaultDir)
+	}
+
+	// Validate exclude patterns
+	for _, pattern := range c.excludeDirs {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("exclude pattern cannot be empty")
+		}
+		if _, err := ignore.Parse(pattern, "."); err != nil {
+			return errors.New("invalid exclude pattern: " + err.Error())
+		}
+	}
+
+	if !indexator.IsMissingMarkersPolicy(c.missingMarkers) {
+		return errors.New("invalid missing markers policy: " + c.missingMarkers +
+			" (expected skip, append or prepend)")
 	}
 
-	// Validate exclude directories
-	for _, dir := range c.excludeDirs {
+	// Validate sort strategies
+	if !indexator.IsSortStrategy(c.sortBy) {
+		return errors.New("invalid sort strategy: " + c.sortBy)
+	}
+	for dir, strategy := range c.sortOverrides {
 		if strings.TrimSpace(dir) == "" {
-			return errors.New("exclude directory cannot be empty")
+			return errors.New("sort override directory cannot be empty")
+		}
+		if !indexator.IsSortStrategy(strategy) {
+			return errors.New("invalid sort strategy for " + dir + ": " + strategy)
+		}
+	}
+
+	// Validate extension to kind mapping
+	for ext, kind := range c.kinds {
+		if strings.Trim(strings.TrimSpace(ext), ".") == "" {
+			return errors.New("kind extension cannot be empty")
+		}
+		if !indexator.IsFileKind(kind) {
+			return errors.New("invalid kind for " + ext + ": " + kind +
+				" (expected notes, canvases or attachments)")
+		}
+	}
+
+	if c.jobs < 1 {
+		return errors.New("jobs must be at least 1: " + strconv.Itoa(c.jobs))
+	}
+
+	if c.debounce <= 0 {
+		return errors.New("debounce must be positive: " + c.debounce.String())
+	}
+
+	if c.backupKeep < 0 {
+		return errors.New("backup keep cannot be negative: " + strconv.Itoa(c.backupKeep))
+	}
+	if c.backupKeepDays < 0 {
+		return errors.New("backup keep days cannot be negative: " + strconv.Itoa(c.backupKeepDays))
+	}
+
+	switch c.report {
+	case "", ReportJSON:
+	default:
+		return errors.New("invalid report format: " + c.report + " (expected json)")
+	}
+	if c.reportFile != "" && c.report == "" {
+		return errors.New("report file requires a report format")
+	}
+
+	switch c.logFormat {
+	case LogFormatText, LogFormatJSON:
+	default:
+		return errors.New("invalid log format: " + c.logFormat + " (expected text or json)")
+	}
+	if c.logLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.logLevel)); err != nil {
+			return errors.New("invalid log level: " + c.logLevel + " (expected debug, info, warn or error)")
+		}
+	}
+	if c.verbose && c.quiet {
+		return errors.New("verbose and quiet cannot be used together")
+	}
+
+	// Validate template file
+	if c.templatePath != "" {
+		text, err := os.ReadFile(c.templatePath)
+		if err != nil {
+			return errors.New("cannot read template file: " + err.Error())
+		}
+		if _, err := template.New(filepath.Base(c.templatePath)).Parse(string(text)); err != nil {
+			return errors.New("invalid template file: " + err.Error())
 		}
 	}
 
@@ -0,0 +1,460 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VaultFileName is the name of the configuration file in the vault root
+const VaultFileName = "obsidian-index.yaml"
+
+// EnvPrefix is the prefix of environment variables read by Load
+const EnvPrefix = "OBSIDIAN_INDEX_"
+
+// File is the format of configuration files. Unset fields keep the value
+// of lower-precedence sources.
+type File struct {
+	Dir            *string           `yaml:"dir"`
+	Verbose        *bool             `yaml:"verbose"`
+	DryRun         *bool             `yaml:"dry_run"`
+	Backup         *bool             `yaml:"backup"`
+	Update         *bool             `yaml:"update"`
+	Exclude        []string          `yaml:"exclude"`
+	MissingMarkers *string           `yaml:"missing_markers"`
+	Template       *string           `yaml:"template"`
+	Sort           *string           `yaml:"sort"`
+	SortDirs       map[string]string `yaml:"sort_dirs"`
+	FoldersFirst   *bool             `yaml:"folders_first"`
+	Group          *bool             `yaml:"group"`
+	Kinds          map[string]string `yaml:"kinds"`
+	Titles         *bool             `yaml:"titles"`
+
+	ObsidianExcludes *bool   `yaml:"obsidian_excludes"`
+	RespectGitignore *bool   `yaml:"respect_gitignore"`
+	Full             *bool   `yaml:"full"`
+	Debounce         *string `yaml:"debounce"`
+	Jobs             *int    `yaml:"jobs"`
+	Diff             *bool   `yaml:"diff"`
+	BackupDir        *string `yaml:"backup_dir"`
+	BackupKeep       *int    `yaml:"backup_keep"`
+	BackupKeepDays   *int    `yaml:"backup_keep_days"`
+	Transaction      *bool   `yaml:"transaction"`
+	KeepGoing        *bool   `yaml:"keep_going"`
+	Report           *string `yaml:"report"`
+	ReportFile       *string `yaml:"report_file"`
+	LogFormat        *string `yaml:"log_format"`
+	LogFile          *string `yaml:"log_file"`
+	LogLevel         *string `yaml:"log_level"`
+	Quiet            *bool   `yaml:"quiet"`
+}
+
+// LoadOptions controls where Load reads configuration from
+type LoadOptions struct {
+	// ConfigFile is an explicit configuration file. When set, the user and
+	// vault configuration files are not read.
+	ConfigFile string
+	// Overrides are applied last, typically from command-line flags that
+	// were set explicitly
+	Overrides []Option
+	// LookupEnv reads environment variables; defaults to os.LookupEnv
+	LookupEnv func(string) (string, bool)
+}
+
+// Load builds a Config from, in increasing order of precedence: defaults,
+// the user configuration file ($XDG_CONFIG_HOME/obsidian-index/config.yaml),
+// the vault configuration file (obsidian-index.yaml in the vault root),
+// OBSIDIAN_INDEX_* environment variables and the overrides. An explicit
+// ConfigFile replaces both configuration files.
+func Load(opts LoadOptions) (*Config, error) {
+	lookupEnv := opts.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		if value, ok := lookupEnv(EnvPrefix + "CONFIG"); ok && value != "" {
+			configFile = value
+		}
+	}
+
+	var files []string
+	if configFile != "" {
+		files = append(files, configFile)
+	} else if userFile := userConfigFile(lookupEnv); userFile != "" {
+		files = append(files, userFile)
+	}
+
+	// The vault directory may come from any source, so resolve it before
+	// looking for the vault configuration file
+	cfg, err := loadLayers(files, opts.Overrides, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if configFile == "" {
+		vaultFile := filepath.Join(cfg.vaultDir, VaultFileName)
+		if _, err := os.Stat(vaultFile); err == nil {
+			files = append(files, vaultFile)
+			if cfg, err = loadLayers(files, opts.Overrides, lookupEnv); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadLayers applies configuration files, the environment and overrides
+// on top of the defaults and resolves the vault directory
+func loadLayers(files []string, overrides []Option, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := New()
+
+	for _, file := range files {
+		f, err := ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.applyFile(f, filepath.Dir(file), filepath.Base(file) == VaultFileName); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %w", file, err)
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range overrides {
+		opt(cfg)
+	}
+
+	if cfg.vaultDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		cfg.vaultDir = wd
+	}
+
+	vaultDir, err := filepath.Abs(cfg.vaultDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	cfg.vaultDir = vaultDir
+
+	if cfg.templatePath != "" {
+		templatePath, err := filepath.Abs(cfg.templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute template path: %w", err)
+		}
+		cfg.templatePath = templatePath
+	}
+
+	if cfg.backupDir != "" {
+		backupDir, err := filepath.Abs(cfg.backupDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute backup directory: %w", err)
+		}
+		cfg.backupDir = backupDir
+	}
+
+	if cfg.reportFile != "" {
+		reportFile, err := filepath.Abs(cfg.reportFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute report file path: %w", err)
+		}
+		cfg.reportFile = reportFile
+	}
+
+	if cfg.logFile != "" {
+		logFile, err := filepath.Abs(cfg.logFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute log file path: %w", err)
+		}
+		cfg.logFile = logFile
+	}
+
+	return cfg, nil
+}
+
+// ReadFile parses a configuration file
+func ReadFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	var f File
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+	}
+
+	return &f, nil
+}
+
+// applyFile applies the fields set in a configuration file. Relative paths
+// are resolved against baseDir. The vault file cannot move the vault.
+func (c *Config) applyFile(f *File, baseDir string, vaultFile bool) error {
+	if f.Dir != nil {
+		if vaultFile {
+			return errors.New("dir cannot be set in the vault configuration file")
+		}
+		c.vaultDir = resolvePath(baseDir, *f.Dir)
+	}
+	if f.Verbose != nil {
+		c.verbose = *f.Verbose
+	}
+	if f.DryRun != nil {
+		c.dryRun = *f.DryRun
+	}
+	if f.Backup != nil {
+		c.backup = *f.Backup
+	}
+	if f.Update != nil {
+		c.update = *f.Update
+	}
+	if f.Exclude != nil {
+		c.excludeDirs = f.Exclude
+	}
+	if f.MissingMarkers != nil {
+		c.missingMarkers = *f.MissingMarkers
+	}
+	if f.Template != nil {
+		c.templatePath = resolvePath(baseDir, *f.Template)
+	}
+	if f.Sort != nil {
+		c.sortBy = *f.Sort
+	}
+	if f.SortDirs != nil {
+		c.sortOverrides = f.SortDirs
+	}
+	if f.FoldersFirst != nil {
+		c.foldersFirst = *f.FoldersFirst
+	}
+	if f.Group != nil {
+		c.group = *f.Group
+	}
+	if f.Kinds != nil {
+		c.kinds = f.Kinds
+	}
+	if f.Titles != nil {
+		c.titles = *f.Titles
+	}
+	if f.ObsidianExcludes != nil {
+		c.obsidianExcludes = *f.ObsidianExcludes
+	}
+	if f.RespectGitignore != nil {
+		c.respectGitignore = *f.RespectGitignore
+	}
+	if f.Full != nil {
+		c.full = *f.Full
+	}
+	if f.Debounce != nil {
+		debounce, err := time.ParseDuration(*f.Debounce)
+		if err != nil {
+			return fmt.Errorf("invalid debounce %q: %w", *f.Debounce, err)
+		}
+		c.debounce = debounce
+	}
+	if f.Jobs != nil {
+		c.jobs = *f.Jobs
+	}
+	if f.Diff != nil {
+		c.diff = *f.Diff
+	}
+	if f.BackupDir != nil {
+		c.backupDir = resolvePath(baseDir, *f.BackupDir)
+	}
+	if f.BackupKeep != nil {
+		c.backupKeep = *f.BackupKeep
+	}
+	if f.BackupKeepDays != nil {
+		c.backupKeepDays = *f.BackupKeepDays
+	}
+	if f.Transaction != nil {
+		c.transaction = *f.Transaction
+	}
+	if f.KeepGoing != nil {
+		c.keepGoing = *f.KeepGoing
+	}
+	if f.Report != nil {
+		c.report = *f.Report
+	}
+	if f.ReportFile != nil {
+		c.reportFile = resolvePath(baseDir, *f.ReportFile)
+	}
+	if f.LogFormat != nil {
+		c.logFormat = *f.LogFormat
+	}
+	if f.LogFile != nil {
+		c.logFile = resolvePath(baseDir, *f.LogFile)
+	}
+	if f.LogLevel != nil {
+		c.logLevel = *f.LogLevel
+	}
+	if f.Quiet != nil {
+		c.quiet = *f.Quiet
+	}
+	return nil
+}
+
+// applyEnv applies OBSIDIAN_INDEX_* environment variables. Lists are comma
+// separated and maps use KEY=VALUE pairs.
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	stringVars := map[string]*string{
+		"DIR":             &c.vaultDir,
+		"MISSING_MARKERS": &c.missingMarkers,
+		"TEMPLATE":        &c.templatePath,
+		"SORT":            &c.sortBy,
+		"BACKUP_DIR":      &c.backupDir,
+		"REPORT":          &c.report,
+		"REPORT_FILE":     &c.reportFile,
+		"LOG_FORMAT":      &c.logFormat,
+		"LOG_FILE":        &c.logFile,
+		"LOG_LEVEL":       &c.logLevel,
+	}
+	for name, target := range stringVars {
+		if value, ok := lookupEnv(EnvPrefix + name); ok {
+			*target = value
+		}
+	}
+
+	boolVars := map[string]*bool{
+		"VERBOSE":       &c.verbose,
+		"DRY_RUN":       &c.dryRun,
+		"BACKUP":        &c.backup,
+		"UPDATE":        &c.update,
+		"FOLDERS_FIRST": &c.foldersFirst,
+		"GROUP":         &c.group,
+		"TITLES":        &c.titles,
+		"FULL":          &c.full,
+		"DIFF":          &c.diff,
+		"TRANSACTION":   &c.transaction,
+		"KEEP_GOING":    &c.keepGoing,
+		"QUIET":         &c.quiet,
+
+		"OBSIDIAN_EXCLUDES": &c.obsidianExcludes,
+		"RESPECT_GITIGNORE": &c.respectGitignore,
+	}
+	for name, target := range boolVars {
+		value, ok := lookupEnv(EnvPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean in %s%s: %q", EnvPrefix, name, value)
+		}
+		*target = parsed
+	}
+
+	if value, ok := lookupEnv(EnvPrefix + "EXCLUDE"); ok {
+		c.excludeDirs = splitList(value)
+	}
+
+	intVars := map[string]*int{
+		"JOBS":             &c.jobs,
+		"BACKUP_KEEP":      &c.backupKeep,
+		"BACKUP_KEEP_DAYS": &c.backupKeepDays,
+	}
+	for name, target := range intVars {
+		value, ok := lookupEnv(EnvPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number in %s%s: %q", EnvPrefix, name, value)
+		}
+		*target = parsed
+	}
+
+	if value, ok := lookupEnv(EnvPrefix + "DEBOUNCE"); ok {
+		debounce, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration in %sDEBOUNCE: %q", EnvPrefix, value)
+		}
+		c.debounce = debounce
+	}
+
+	mapVars := map[string]*map[string]string{
+		"SORT_DIRS": &c.sortOverrides,
+		"KINDS":     &c.kinds,
+	}
+	for name, target := range mapVars {
+		value, ok := lookupEnv(EnvPrefix + name)
+		if !ok {
+			continue
+		}
+		parsed, err := parseMap(value)
+		if err != nil {
+			return fmt.Errorf("invalid value in %s%s: %w", EnvPrefix, name, err)
+		}
+		*target = parsed
+	}
+
+	return nil
+}
+
+// userConfigFile returns the user configuration file if it exists
+func userConfigFile(lookupEnv func(string) (string, bool)) string {
+	configHome, ok := lookupEnv("XDG_CONFIG_HOME")
+	if !ok || configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	path := filepath.Join(configHome, "obsidian-index", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseMap(value string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, pair := range splitList(value) {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected KEY=VALUE, got %q", pair)
+		}
+		parsed[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return parsed, nil
+}
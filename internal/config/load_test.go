@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nzb3/obsidian-index/internal/indexator"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	configHome := t.TempDir()
+	vault := t.TempDir()
+
+	writeFile(t, filepath.Join(configHome, "obsidian-index", "config.yaml"), `
+dir: `+vault+`
+sort: natural
+group: true
+titles: true
+exclude: [templates]
+`)
+	writeFile(t, filepath.Join(vault, VaultFileName), `
+sort: mtime
+folders_first: true
+template: templates/index.tmpl
+`)
+
+	cfg, err := Load(LoadOptions{
+		LookupEnv: envLookup(map[string]string{
+			"XDG_CONFIG_HOME":          configHome,
+			"OBSIDIAN_INDEX_GROUP":     "false",
+			"OBSIDIAN_INDEX_EXCLUDE":   "archive, drafts",
+			"OBSIDIAN_INDEX_SORT_DIRS": "Journal=ctime",
+		}),
+		Overrides: []Option{WithSortBy(indexator.SortOrder)},
+	})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.GetVaultDir() != vault {
+		t.Errorf("vault dir = %q, want %q from user config", cfg.GetVaultDir(), vault)
+	}
+	if cfg.GetSortBy() != indexator.SortOrder {
+		t.Errorf("sort = %q, want %q from overrides", cfg.GetSortBy(), indexator.SortOrder)
+	}
+	if !cfg.IsFoldersFirst() {
+		t.Error("folders first should be set by the vault config")
+	}
+	if cfg.IsGroup() {
+		t.Error("group should be disabled by the environment")
+	}
+	if !cfg.IsTitles() {
+		t.Error("titles should be enabled by the user config")
+	}
+	if expected := []string{"archive", "drafts"}; !reflect.DeepEqual(cfg.GetExcludeDirs(), expected) {
+		t.Errorf("exclude = %v, want %v from environment", cfg.GetExcludeDirs(), expected)
+	}
+	if expected := map[string]string{"Journal": indexator.SortCreateTime}; !reflect.DeepEqual(cfg.GetSortOverrides(), expected) {
+		t.Errorf("sort overrides = %v, want %v", cfg.GetSortOverrides(), expected)
+	}
+	if expected := filepath.Join(vault, "templates", "index.tmpl"); cfg.GetTemplatePath() != expected {
+		t.Errorf("template = %q, want %q relative to the vault config", cfg.GetTemplatePath(), expected)
+	}
+}
+
+func TestLoad_ExplicitConfigFile(t *testing.T) {
+	configHome := t.TempDir()
+	vault := t.TempDir()
+
+	writeFile(t, filepath.Join(configHome, "obsidian-index", "config.yaml"), "titles: true\n")
+	writeFile(t, filepath.Join(vault, VaultFileName), "group: true\n")
+	explicit := filepath.Join(t.TempDir(), "ci.yaml")
+	writeFile(t, explicit, "update: true\n")
+
+	cfg, err := Load(LoadOptions{
+		ConfigFile: explicit,
+		LookupEnv:  envLookup(map[string]string{"XDG_CONFIG_HOME": configHome}),
+		Overrides:  []Option{WithVaultDir(vault)},
+	})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if !cfg.IsUpdate() {
+		t.Error("update should be set by the explicit config file")
+	}
+	if cfg.IsTitles() || cfg.IsGroup() {
+		t.Error("user and vault config files should be ignored with an explicit config file")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	vault := t.TempDir()
+
+	tests := []struct {
+		name      string
+		vaultFile string
+		env       map[string]string
+	}{
+		{
+			name:      "unknown field",
+			vaultFile: "sorting: natural\n",
+		},
+		{
+			name:      "dir in vault file",
+			vaultFile: "dir: /elsewhere\n",
+		},
+		{
+			name: "invalid boolean",
+			env:  map[string]string{"OBSIDIAN_INDEX_GROUP": "maybe"},
+		},
+		{
+			name: "invalid map",
+			env:  map[string]string{"OBSIDIAN_INDEX_KINDS": "excalidraw"},
+		},
+		{
+			name: "invalid jobs",
+			env:  map[string]string{"OBSIDIAN_INDEX_JOBS": "many"},
+		},
+		{
+			name:      "invalid debounce",
+			vaultFile: "debounce: soon\n",
+		},
+		{
+			name: "invalid backup keep",
+			env:  map[string]string{"OBSIDIAN_INDEX_BACKUP_KEEP": "all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(filepath.Join(vault, VaultFileName))
+			if tt.vaultFile != "" {
+				writeFile(t, filepath.Join(vault, VaultFileName), tt.vaultFile)
+			}
+
+			env := map[string]string{"XDG_CONFIG_HOME": t.TempDir()}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+
+			_, err := Load(LoadOptions{
+				LookupEnv: envLookup(env),
+				Overrides: []Option{WithVaultDir(vault)},
+			})
+			if err == nil {
+				t.Error("Load() should fail")
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	vault := t.TempDir()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "defaults", wantErr: false},
+		{name: "invalid sort", opts: []Option{WithSortBy("random")}, wantErr: true},
+		{name: "invalid sort override", opts: []Option{WithSortOverrides(map[string]string{"Journal": "random"})}, wantErr: true},
+		{name: "invalid kind", opts: []Option{WithKinds(map[string]string{".pdf": "papers"})}, wantErr: true},
+		{name: "invalid missing markers policy", opts: []Option{WithMissingMarkers("replace")}, wantErr: true},
+		{name: "missing template", opts: []Option{WithTemplatePath(filepath.Join(vault, "missing.tmpl"))}, wantErr: true},
+		{name: "empty exclude", opts: []Option{WithExcludeDirs([]string{" "})}, wantErr: true},
+		{name: "zero jobs", opts: []Option{WithJobs(0)}, wantErr: true},
+		{name: "negative debounce", opts: []Option{WithDebounce(-time.Second)}, wantErr: true},
+		{name: "negative backup keep", opts: []Option{WithBackupKeep(-1)}, wantErr: true},
+		{name: "json report", opts: []Option{WithReport(ReportJSON), WithReportFile(filepath.Join(vault, "report.json"))}, wantErr: false},
+		{name: "invalid report format", opts: []Option{WithReport("xml")}, wantErr: true},
+		{name: "report file without format", opts: []Option{WithReportFile(filepath.Join(vault, "report.json"))}, wantErr: true},
+		{name: "json logs", opts: []Option{WithLogFormat(LogFormatJSON), WithLogLevel("warn")}, wantErr: false},
+		{name: "invalid log format", opts: []Option{WithLogFormat("xml")}, wantErr: true},
+		{name: "invalid log level", opts: []Option{WithLogLevel("loud")}, wantErr: true},
+		{name: "verbose and quiet", opts: []Option{WithVerbose(true), WithQuiet(true)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewWithAllOptions(vault, false, false, false, nil, tt.opts...)
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing vault", func(t *testing.T) {
+		cfg := NewWithOptions(filepath.Join(vault, "missing"), false)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidVault) {
+			t.Errorf("Validate() error = %v, want ErrInvalidVault", err)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package config
+
+import "time"
+
+// Option configures optional settings of a Config
+type Option func(*Config)
+
+// WithVaultDir sets the vault directory
+func WithVaultDir(vaultDir string) Option {
+	return func(c *Config) {
+		c.vaultDir = vaultDir
+	}
+}
+
+// WithVerbose enables verbose output
+func WithVerbose(verbose bool) Option {
+	return func(c *Config) {
+		c.verbose = verbose
+	}
+}
+
+// WithDryRun enables dry run mode
+func WithDryRun(dryRun bool) Option {
+	return func(c *Config) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithBackup enables backups of existing index files
+func WithBackup(backup bool) Option {
+	return func(c *Config) {
+		c.backup = backup
+	}
+}
+
+// WithExcludeDirs sets the directories excluded from indexing
+func WithExcludeDirs(excludeDirs []string) Option {
+	return func(c *Config) {
+		c.excludeDirs = excludeDirs
+	}
+}
+
+// WithMissingMarkers sets the policy for existing index files without markers
+func WithMissingMarkers(policy string) Option {
+	return func(c *Config) {
+		c.missingMarkers = policy
+	}
+}
+
+// WithTemplatePath sets the template used to render index files
+func WithTemplatePath(path string) Option {
+	return func(c *Config) {
+		c.templatePath = path
+	}
+}
+
+// WithSortBy sets the global sort strategy for index entries
+func WithSortBy(strategy string) Option {
+	return func(c *Config) {
+		c.sortBy = strategy
+	}
+}
+
+// WithSortOverrides sets per-directory sort strategies
+func WithSortOverrides(overrides map[string]string) Option {
+	return func(c *Config) {
+		c.sortOverrides = overrides
+	}
+}
+
+// WithFoldersFirst lists subfolders before files
+func WithFoldersFirst(foldersFirst bool) Option {
+	return func(c *Config) {
+		c.foldersFirst = foldersFirst
+	}
+}
+
+// WithGroup splits index files into sections by entry kind
+func WithGroup(group bool) Option {
+	return func(c *Config) {
+		c.group = group
+	}
+}
+
+// WithKinds maps file extensions to entry kinds
+func WithKinds(kinds map[string]string) Option {
+	return func(c *Config) {
+		c.kinds = kinds
+	}
+}
+
+// WithTitles uses note titles as link aliases and drops .md from link targets
+func WithTitles(titles bool) Option {
+	return func(c *Config) {
+		c.titles = titles
+	}
+}
+
+// WithUpdate enables regeneration of index files previously created by the tool
+func WithUpdate(update bool) Option {
+	return func(c *Config) {
+		c.update = update
+	}
+}
+
+// WithObsidianExcludes honours the "Excluded files" setting in .obsidian/app.json
+func WithObsidianExcludes(obsidianExcludes bool) Option {
+	return func(c *Config) {
+		c.obsidianExcludes = obsidianExcludes
+	}
+}
+
+// WithRespectGitignore excludes paths ignored by the .gitignore files of the vault
+func WithRespectGitignore(respectGitignore bool) Option {
+	return func(c *Config) {
+		c.respectGitignore = respectGitignore
+	}
+}
+
+// WithDebounce sets how long watch mode waits for the vault to settle
+func WithDebounce(debounce time.Duration) Option {
+	return func(c *Config) {
+		c.debounce = debounce
+	}
+}
+
+// WithJobs sets the number of directories indexed concurrently
+func WithJobs(jobs int) Option {
+	return func(c *Config) {
+		c.jobs = jobs
+	}
+}
+
+// WithDiff prints a unified diff of every index change
+func WithDiff(diff bool) Option {
+	return func(c *Config) {
+		c.diff = diff
+	}
+}
+
+// WithFull rebuilds every index file instead of only those that changed
+func WithFull(full bool) Option {
+	return func(c *Config) {
+		c.full = full
+	}
+}
+
+// WithBackupDir sets where backups are stored
+func WithBackupDir(backupDir string) Option {
+	return func(c *Config) {
+		c.backupDir = backupDir
+	}
+}
+
+// WithBackupKeep sets the number of backup runs kept, 0 keeps all
+func WithBackupKeep(keep int) Option {
+	return func(c *Config) {
+		c.backupKeep = keep
+	}
+}
+
+// WithBackupKeepDays sets how many days backup runs are kept, 0 keeps them
+// regardless of age
+func WithBackupKeepDays(days int) Option {
+	return func(c *Config) {
+		c.backupKeepDays = days
+	}
+}
+
+// WithTransaction stages the changes of a run and only writes them once
+// every directory was indexed
+func WithTransaction(transaction bool) Option {
+	return func(c *Config) {
+		c.transaction = transaction
+	}
+}
+
+// WithKeepGoing carries on indexing the other directories when one fails
+func WithKeepGoing(keepGoing bool) Option {
+	return func(c *Config) {
+		c.keepGoing = keepGoing
+	}
+}
+
+// WithReport sets the format of the run report; empty disables it
+func WithReport(format string) Option {
+	return func(c *Config) {
+		c.report = format
+	}
+}
+
+// WithReportFile sets where the run report is written; empty means stdout
+func WithReportFile(path string) Option {
+	return func(c *Config) {
+		c.reportFile = path
+	}
+}
+
+// WithLogFormat sets the format of log records, text or json
+func WithLogFormat(format string) Option {
+	return func(c *Config) {
+		c.logFormat = format
+	}
+}
+
+// WithLogFile sets where logs are written; empty means stderr
+func WithLogFile(path string) Option {
+	return func(c *Config) {
+		c.logFile = path
+	}
+}
+
+// WithLogLevel sets the minimum level logged: debug, info, warn or error
+func WithLogLevel(level string) Option {
+	return func(c *Config) {
+		c.logLevel = level
+	}
+}
+
+// WithQuiet only logs errors and drops status lines
+func WithQuiet(quiet bool) Option {
+	return func(c *Config) {
+		c.quiet = quiet
+	}
+}
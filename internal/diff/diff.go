@@ -0,0 +1,301 @@
+// Package diff renders line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change
+const Context = 3
+
+// DevNull is the file name used for the missing side of a created or
+// removed file
+const DevNull = "/dev/null"
+
+// ANSI escape sequences used by Colorize
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorCyan   = "\x1b[36m"
+	noNewlineAt = "\\ No newline at end of file"
+)
+
+// op is a single line of an edit script: ' ' keeps, '-' deletes and '+'
+// inserts a line
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff between oldText and newText, labelled
+// with oldName and newName. It returns an empty string if the texts are
+// equal.
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	ops := edits(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", formatRange(h.oldStart, h.oldLines), formatRange(h.newStart, h.newLines))
+		for _, o := range h.ops {
+			b.WriteByte(o.kind)
+			if strings.HasSuffix(o.line, "\n") {
+				b.WriteString(o.line)
+			} else {
+				b.WriteString(o.line)
+				b.WriteString("\n" + noNewlineAt + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// Colorize adds ANSI colours to a unified diff for display on a terminal
+func Colorize(unified string) string {
+	if unified == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "+++ "):
+			color = colorBold
+		case strings.HasPrefix(text, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(text, "-"):
+			color = colorRed
+		case strings.HasPrefix(text, "+"):
+			color = colorGreen
+		}
+		if color == "" {
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString(color + text + colorReset + "\n")
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines that keep their trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits returns a shortest edit script turning a into b. It uses the
+// linear-space variant of Myers' algorithm, so memory stays proportional to
+// the number of lines however large the files are.
+func edits(a, b []string) []op {
+	d := &differ{
+		a:        a,
+		b:        b,
+		deleted:  make([]bool, len(a)),
+		inserted: make([]bool, len(b)),
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	// Deletions come before the insertions that replace them
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && d.deleted[i]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		case j < len(b) && d.inserted[j]:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		default:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		}
+	}
+	return ops
+}
+
+// differ marks the lines of a that are deleted and the lines of b that are
+// inserted by a shortest edit script
+type differ struct {
+	a, b              []string
+	deleted, inserted []bool
+}
+
+// compare marks the edits turning a[aLo:aHi] into b[bLo:bHi]. Common
+// prefixes and suffixes are stripped, then the ranges are split at the
+// middle snake of a shortest edit script and both halves compared.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for j := bLo; j < bHi; j++ {
+			d.inserted[j] = true
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			d.deleted[i] = true
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(u, aHi, v, bHi)
+	}
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the middle snake
+// of a shortest edit script turning a[aLo:aHi] into b[bLo:bHi], searching
+// from both ends at once. The ranges must not both be empty.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// forward[k] is the furthest x reached from the start on diagonal
+	// k = x - y, backward[k] the furthest distance from the end on the
+	// reversed diagonal k
+	forward := make([]int, 2*limit+3)
+	backward := make([]int, 2*limit+3)
+
+	for steps := 0; steps <= limit; steps++ {
+		for k := -steps; k <= steps; k += 2 {
+			var x int
+			if k == -steps || k != steps && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if c := delta - k; odd && c >= -(steps-1) && c <= steps-1 && x+backward[offset+c] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		for c := -steps; c <= steps; c += 2 {
+			var x int
+			if c == -steps || c != steps && backward[offset+c-1] < backward[offset+c+1] {
+				x = backward[offset+c+1]
+			} else {
+				x = backward[offset+c-1] + 1
+			}
+			y := x - c
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+c] = x
+
+			if k := delta - c; !odd && k >= -steps && k <= steps && x+forward[offset+k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	// Unreachable: the searches always meet within limit steps
+	return aLo, bLo, aHi, bHi
+}
+
+// hunk is a group of changes with their surrounding context
+type hunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	ops                []op
+}
+
+// hunks groups an edit script into hunks with Context lines of context.
+// Changes separated by at most twice the context share a hunk.
+func hunks(ops []op) []hunk {
+	var result []hunk
+
+	// oldLine and newLine count the lines before ops[i]
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, o := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if o.kind != '+' {
+			oldLine[i+1]++
+		}
+		if o.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := max(i-Context, 0)
+		end := i + 1
+		for unchanged := 0; end < len(ops) && unchanged <= 2*Context; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Trim trailing context down to Context lines
+		for end > i && ops[end-1].kind == ' ' {
+			end--
+		}
+		lastChange := end
+		end = min(lastChange+Context, len(ops))
+
+		result = append(result, hunk{
+			oldStart: oldLine[start] + 1,
+			oldLines: oldLine[end] - oldLine[start],
+			newStart: newLine[start] + 1,
+			newLines: newLine[end] - newLine[start],
+			ops:      ops[start:end],
+		})
+		i = lastChange
+	}
+	return result
+}
+
+// formatRange formats a hunk range. An empty range starts at the line
+// before the hunk, as in GNU diff.
+func formatRange(start, lines int) string {
+	switch lines {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
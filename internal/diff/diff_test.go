@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	alphabet := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{
+			name:    "equal",
+			oldText: alphabet,
+			newText: alphabet,
+			want:    "",
+		},
+		{
+			name:    "separate hunks",
+			oldText: alphabet,
+			newText: strings.Replace(alphabet, "b\n", "B\n", 1) + "n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -11,3 +11,4 @@\n k\n l\n m\n+n\n\\ No newline at end of file\n",
+		},
+		{
+			name:    "nearby changes share a hunk",
+			oldText: alphabet,
+			newText: strings.NewReplacer("b\n", "", "h\n", "H\n").Replace(alphabet),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,11 +1,10 @@\n a\n-b\n c\n d\n e\n f\n g\n-h\n+H\n i\n j\n k\n",
+		},
+		{
+			name:    "new file",
+			oldText: "",
+			newText: "a\nb\n",
+			want:    "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:    "removed file",
+			oldText: "a\n",
+			newText: "",
+			want:    "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.oldText, tt.newText); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified_LargeFile(t *testing.T) {
+	// An LCS table over these files would hold 400 million cells
+	var oldText, newText strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&oldText, "line %d\n", i)
+		if i%100 == 0 {
+			fmt.Fprintf(&newText, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&newText, "line %d\n", i)
+		}
+	}
+
+	got := Unified("old", "new", oldText.String(), newText.String())
+	var removed, added int
+	for _, line := range strings.Split(got, "\n") {
+		switch {
+		case strings.HasPrefix(line, "-line "):
+			removed++
+		case strings.HasPrefix(line, "+changed "):
+			added++
+		}
+	}
+	if removed != 200 || added != 200 {
+		t.Errorf("Unified() removed %d and added %d lines, want 200 each", removed, added)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	got := Colorize("--- old\n+++ new\n@@ -1 +1 @@\n-a\n+b\n c\n")
+	want := colorBold + "--- old" + colorReset + "\n" +
+		colorBold + "+++ new" + colorReset + "\n" +
+		colorCyan + "@@ -1 +1 @@" + colorReset + "\n" +
+		colorRed + "-a" + colorReset + "\n" +
+		colorGreen + "+b" + colorReset + "\n" +
+		" c\n"
+	if got != want {
+		t.Errorf("Colorize() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+// Package ignore implements gitignore-style path patterns.
+//
+// Patterns are matched against slash-separated paths relative to the
+// directory that declared them:
+//
+//   - a pattern without a slash, like "Templates" or "*.pdf", matches a name
+//     at any depth
+//   - a pattern with a leading or inner slash, like "/Templates" or
+//     "Notes/drafts", is anchored to the declaring directory
+//   - a trailing slash, like "build/", only matches directories
+//   - a leading "!" negates the pattern and re-includes matching paths
+//   - "*", "?", "[...]", "{a,b}" and "**" have doublestar glob semantics
+//
+// When several patterns match a path the last one wins.
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Pattern is a single compiled pattern
+type Pattern struct {
+	base    string
+	glob    string
+	negate  bool
+	dirOnly bool
+}
+
+// Parse compiles a pattern declared in the vault-relative directory base
+// ("." for the vault root)
+func Parse(pattern, base string) (Pattern, error) {
+	p := Pattern{base: cleanPath(base)}
+
+	glob := strings.TrimSpace(pattern)
+	if strings.HasPrefix(glob, "!") {
+		p.negate = true
+		glob = glob[1:]
+	} else if strings.HasPrefix(glob, `\!`) || strings.HasPrefix(glob, `\#`) {
+		glob = glob[1:]
+	}
+
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimLeft(glob, "/")
+	if glob == "" {
+		return Pattern{}, fmt.Errorf("empty pattern %q", pattern)
+	}
+	if !anchored && !strings.HasPrefix(glob, "**") {
+		glob = "**/" + glob
+	}
+
+	if !doublestar.ValidatePattern(glob) {
+		return Pattern{}, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	p.glob = glob
+
+	return p, nil
+}
+
+// ParseLines compiles the patterns of a gitignore file, skipping blank lines
+// and comments
+func ParseLines(content, base string) ([]Pattern, error) {
+	var patterns []Pattern
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := Parse(line, base)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// Match reports whether the pattern matches a vault-relative path
+func (p Pattern) Match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	rel := cleanPath(relPath)
+	if p.base != "." {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, p.base+"/")
+	}
+	if rel == "." {
+		return false
+	}
+
+	matched, err := doublestar.Match(p.glob, rel)
+	return err == nil && matched
+}
+
+// Excluded evaluates patterns in order and reports whether relPath is
+// excluded. The last matching pattern decides.
+func Excluded(patterns []Pattern, relPath string, isDir bool) bool {
+	excluded := false
+	for _, p := range patterns {
+		if p.Match(relPath, isDir) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+func cleanPath(p string) string {
+	clean := path.Clean(strings.Trim(p, "/"))
+	if clean == "" {
+		return "."
+	}
+	return clean
+}
@@ -0,0 +1,93 @@
+package ignore
+
+import "testing"
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		base     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"bare name matches directory at root", "art", ".", "art", true, true},
+		{"bare name matches nested directory", "art", ".", "Projects/art", true, true},
+		{"bare name does not match substring", "art", ".", "Smart Notes", true, false},
+		{"bare name does not match inside name", "art", ".", "Departments", true, false},
+		{"bare name matches file", "secret.md", ".", "notes/secret.md", false, true},
+		{"extension glob matches file", "*.pdf", ".", "docs/spec.pdf", false, true},
+		{"anchored pattern matches at root", "/Templates", ".", "Templates", true, true},
+		{"anchored pattern does not match nested", "/Templates", ".", "Notes/Templates", true, false},
+		{"inner slash anchors pattern", "Notes/drafts", ".", "Notes/drafts", true, true},
+		{"inner slash does not match nested", "Notes/drafts", ".", "Archive/Notes/drafts", true, false},
+		{"doublestar matches any depth", "Notes/**/tmp", ".", "Notes/a/b/tmp", true, true},
+		{"trailing slash matches directory", "build/", ".", "build", true, true},
+		{"trailing slash does not match file", "build/", ".", "build", false, false},
+		{"pattern is relative to base", "drafts", "Notes", "Notes/x/drafts", true, true},
+		{"pattern does not match outside base", "drafts", "Notes", "Other/drafts", true, false},
+		{"anchored pattern relative to base", "/drafts", "Notes", "Notes/drafts", true, true},
+		{"base itself never matches", "Notes", "Notes", "Notes", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.pattern, tt.base)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.pattern, err)
+			}
+			if got := p.Match(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExcluded_Negation(t *testing.T) {
+	var patterns []Pattern
+	for _, raw := range []string{"Templates/*", "!Templates/keep", "*.tmp"} {
+		p, err := Parse(raw, ".")
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", raw, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	tests := map[string]bool{
+		"Templates":          false,
+		"Templates/daily":    true,
+		"Templates/keep":     false,
+		"Notes/scratch.tmp":  true,
+		"Notes/scratch.md":   false,
+		"Templates/keep.tmp": true,
+	}
+	for path, expected := range tests {
+		if got := Excluded(patterns, path, false); got != expected {
+			t.Errorf("Excluded(%q) = %v, want %v", path, got, expected)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "!", "/", "[unclosed"} {
+		if _, err := Parse(raw, "."); err == nil {
+			t.Errorf("Parse(%q) should fail", raw)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	patterns, err := ParseLines("# comment\n\n*.pdf\r\n!keep.pdf\n\\#literal\n", ".")
+	if err != nil {
+		t.Fatalf("ParseLines() failed: %v", err)
+	}
+	if len(patterns) != 3 {
+		t.Fatalf("ParseLines() returned %d patterns, want 3", len(patterns))
+	}
+	if Excluded(patterns, "keep.pdf", false) {
+		t.Error("keep.pdf should be re-included")
+	}
+	if !Excluded(patterns, "#literal", false) {
+		t.Error("escaped # should be a literal pattern")
+	}
+}
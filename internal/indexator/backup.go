@@ -0,0 +1,266 @@
+package indexator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupsDirName is the default backup store inside StateDirName
+const backupsDirName = "backups"
+
+// ErrNoBackup is returned by Restore when there is nothing to restore
+var ErrNoBackup = errors.New("no backup found")
+
+// BackupRun is a run that backed up index files
+type BackupRun struct {
+	// ID names the directory of the run in the backup store
+	ID   string
+	Time time.Time
+	// Files are the vault-relative paths of the backed up files, sorted
+	Files []string
+}
+
+// backupRoot returns the directory of the backup store
+func (idx *Indexator) backupRoot() string {
+	if idx.backupDir != "" {
+		return idx.backupDir
+	}
+	return filepath.Join(idx.vaultPath, StateDirName, backupsDirName)
+}
+
+// inBackupStore reports whether a vault-relative path lies in a backup
+// store configured inside the vault. The default store is in a hidden
+// directory, which is never indexed anyway.
+func (idx *Indexator) inBackupStore(relPath string) bool {
+	if idx.backupDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(idx.vaultPath, idx.backupDir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return relPath == rel || strings.HasPrefix(relPath, rel+"/")
+}
+
+// backupRunDir returns the directory of the current run in the backup
+// store, creating it the first time
+func (idx *Indexator) backupRunDir() (string, error) {
+	runDir := filepath.Join(idx.backupRoot(), idx.currentRunID())
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return runDir, nil
+}
+
+// backupExistingFile moves an existing file into the current run of the
+// backup store, under its vault-relative path
+func (idx *Indexator) backupExistingFile(filePath string) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil // No file to backup
+	}
+
+	runDir, err := idx.backupRunDir()
+	if err != nil {
+		return err
+	}
+	backupPath := filepath.Join(runDir, filepath.FromSlash(idx.getRelativePath(filePath)))
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Keep the content for the journal
+	var before []byte
+	if idx.journaling() {
+		before, _ = os.ReadFile(filePath)
+	}
+
+	if err := moveFile(filePath, backupPath); err != nil {
+		return fmt.Errorf("failed to create backup %s: %w", backupPath, err)
+	}
+	idx.record(journalBackup, filePath, before, nil)
+	idx.report.backedUp(filePath, idx.reportPath(backupPath))
+
+	slog.Info("Created backup", "original", filePath, "backup", backupPath)
+	return nil
+}
+
+// moveFile renames a file, copying it when the backup store is on another
+// file system
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// pruneBackups removes the runs of the backup store beyond the retention
+// settings, after a run that may have added one. Failures are only logged.
+func (idx *Indexator) pruneBackups() {
+	if !idx.backup || idx.dryRun || idx.backupKeep <= 0 && idx.backupKeepDays <= 0 {
+		return
+	}
+
+	runs, err := idx.BackupRuns()
+	if err != nil {
+		slog.Warn("failed to list backups", "error", err)
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -idx.backupKeepDays)
+	for i, run := range runs {
+		// Runs are sorted newest first
+		tooMany := idx.backupKeep > 0 && i >= idx.backupKeep
+		tooOld := idx.backupKeepDays > 0 && run.Time.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(idx.backupRoot(), run.ID)); err != nil {
+			slog.Warn("failed to remove old backups", "run", run.ID, "error", err)
+			continue
+		}
+		slog.Info("Removed old backups", "run", run.ID, "files", len(run.Files))
+	}
+}
+
+// BackupRuns lists the runs in the backup store, newest first
+func (idx *Indexator) BackupRuns() ([]BackupRun, error) {
+	root := idx.backupRoot()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var runs []BackupRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runTime, ok := runIDTime(entry.Name())
+		if !ok {
+			continue
+		}
+
+		run := BackupRun{ID: entry.Name(), Time: runTime}
+		runDir := filepath.Join(root, entry.Name())
+		err = filepath.WalkDir(runDir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(runDir, filePath)
+			if err != nil {
+				return err
+			}
+			run.Files = append(run.Files, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup run %s: %w", run.ID, err)
+		}
+		sort.Strings(run.Files)
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return newerRun(runs[i].ID, runs[j].ID)
+	})
+	return runs, nil
+}
+
+// Restore copies the files of a run in the backup store back into the
+// vault, overwriting their current versions, and returns their
+// vault-relative paths. An empty runID selects the latest run. Paths
+// restrict the restore to the given files or directories. The backups are
+// kept, so a restore can be repeated.
+func (idx *Indexator) Restore(runID string, paths []string) ([]string, error) {
+	runs, err := idx.BackupRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoBackup, idx.backupRoot())
+	}
+
+	run := runs[0]
+	if runID != "" {
+		found := false
+		for _, r := range runs {
+			if r.ID == runID {
+				run, found = r, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w for run %s", ErrNoBackup, runID)
+		}
+	}
+
+	files := run.Files
+	if len(paths) > 0 {
+		files = nil
+		for _, p := range paths {
+			if filepath.IsAbs(p) {
+				p = idx.getRelativePath(p)
+			}
+			p = cleanRelPath(p)
+
+			matched := false
+			for _, file := range run.Files {
+				if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+					files = append(files, file)
+					matched = true
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("%w for %s in run %s", ErrNoBackup, p, run.ID)
+			}
+		}
+		slices.Sort(files)
+		files = slices.Compact(files)
+	}
+
+	idx.beginRun()
+	defer idx.endRun()
+
+	runDir := filepath.Join(idx.backupRoot(), run.ID)
+	for _, file := range files {
+		target := filepath.Join(idx.vaultPath, filepath.FromSlash(file))
+		if idx.dryRun {
+			slog.Info("DRY RUN: Would restore file", "file", target, "run", run.ID)
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(runDir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup of %s: %w", file, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", file, err)
+		}
+		if err := idx.writeFileAtomic(target, content); err != nil {
+			return nil, err
+		}
+		slog.Info("Restored file", "file", target, "run", run.ID)
+	}
+	return files, nil
+}
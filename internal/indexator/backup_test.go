@@ -0,0 +1,85 @@
+package indexator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexator_Backups(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/a.md": "# A",
+	})
+	if err := NewIndexator(tempDir).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	// Each run with changes backs up the index files it rewrites
+	for _, note := range []string{"b", "c", "d"} {
+		writeTestFiles(t, tempDir, map[string]string{"A/" + note + ".md": "# " + note})
+		indexator := NewIndexatorWithOptions(tempDir, Options{Update: true, Backup: true, BackupKeep: 2})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+	}
+
+	runs, err := NewIndexator(tempDir).BackupRuns()
+	if err != nil {
+		t.Fatalf("BackupRuns() failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("BackupRuns() returned %d runs, want 2 kept by BackupKeep", len(runs))
+	}
+	// The root index did not change, so only the index of A was backed up
+	if want := []string{"A/A.md"}; !reflect.DeepEqual(runs[1].Files, want) {
+		t.Errorf("Backed up files = %v, want %v", runs[1].Files, want)
+	}
+
+	t.Run("restore a file from a run", func(t *testing.T) {
+		restored, err := NewIndexator(tempDir).Restore(runs[1].ID, []string{"A"})
+		if err != nil {
+			t.Fatalf("Restore() failed: %v", err)
+		}
+		if !reflect.DeepEqual(restored, []string{"A/A.md"}) {
+			t.Errorf("Restore() = %v, want [A/A.md]", restored)
+		}
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "A/A.md"))
+		if err != nil {
+			t.Fatalf("Failed to read restored index: %v", err)
+		}
+		// The older run kept the index listing a, b
+		if want := generatedMarker + "\n[[A/a.md]]\n[[A/b.md]]\n"; string(content) != want {
+			t.Errorf("Restored index = %q, want %q", content, want)
+		}
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		if _, err := NewIndexator(tempDir).Restore("19990101_000000", nil); !errors.Is(err, ErrNoBackup) {
+			t.Errorf("Restore() error = %v, want ErrNoBackup", err)
+		}
+	})
+
+	t.Run("custom store inside the vault is not indexed", func(t *testing.T) {
+		backupDir := filepath.Join(tempDir, "Backups")
+		writeTestFiles(t, tempDir, map[string]string{"A/e.md": "# e"})
+		indexator := NewIndexatorWithOptions(tempDir, Options{Update: true, Backup: true, BackupDir: backupDir})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		if _, err := os.Stat(backupDir); err != nil {
+			t.Fatalf("Backups should be stored in BackupDir: %v", err)
+		}
+
+		root, err := os.ReadFile(filepath.Join(tempDir, "index.md"))
+		if err != nil {
+			t.Fatalf("Failed to read root index: %v", err)
+		}
+		if want := generatedMarker + "\n[[A/A.md]]\n"; string(root) != want {
+			t.Errorf("Root index = %q, want %q", root, want)
+		}
+	})
+}
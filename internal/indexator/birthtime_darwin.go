@@ -0,0 +1,19 @@
+//go:build darwin
+
+package indexator
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns the creation time of a file, falling back to the
+// modification time when it is not available
+func birthTime(_ string, info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec)
+}
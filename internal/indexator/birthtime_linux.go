@@ -0,0 +1,23 @@
+//go:build linux
+
+package indexator
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime returns the creation time of a file, falling back to the
+// modification time when the filesystem does not record it
+func birthTime(filePath string, info os.FileInfo) time.Time {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, filePath, 0, unix.STATX_BTIME, &stx); err != nil {
+		return info.ModTime()
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return info.ModTime()
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package indexator
+
+import (
+	"os"
+	"time"
+)
+
+// birthTime falls back to the modification time on platforms that do not
+// expose file creation times
+func birthTime(_ string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package indexator
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns the creation time of a file, falling back to the
+// modification time when it is not available
+func birthTime(_ string, info os.FileInfo) time.Time {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds())
+}
@@ -0,0 +1,171 @@
+package indexator
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/nzb3/obsidian-index/internal/diff"
+)
+
+// Action is what a run does, or would do, to an index file
+type Action string
+
+// Actions on index files
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionRemove Action = "remove"
+)
+
+// Change is a change to a single index file
+type Change struct {
+	Action Action
+	// Path is the vault-relative path of the index file
+	Path string
+	// Old is the current content of the file, empty when it is created
+	Old string
+	// New is the content the file should have, empty when it is removed
+	New string
+	// Entries is the number of entries listed in the new index
+	Entries int
+
+	absPath string
+	// staged is the file New was staged in by a transactional run
+	staged string
+}
+
+// planIndexFile computes the change that brings the index file of a
+// directory up to date, or nil if there is nothing to do
+func (idx *Indexator) planIndexFile(dirPath string, data *IndexData) (*Change, error) {
+	settings := idx.settingsOrBase(idx.getRelativePath(dirPath))
+	indexFilePath := filepath.Join(dirPath, settings.indexFileName(idx.dirName(dirPath)))
+
+	body, err := renderIndex(data, settings)
+	if err != nil {
+		slog.Error("failed to render index", "file", indexFilePath, "error", err)
+		return nil, err
+	}
+
+	existing, err := os.ReadFile(indexFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		slog.Error("failed to read index file", "file", indexFilePath, "error", err)
+		return nil, fmt.Errorf("failed to read index file %s: %w", indexFilePath, err)
+	}
+	exists := err == nil
+
+	relDir := idx.getRelativePath(dirPath)
+	content, ok := mergeIndexContent(string(existing), exists, body, idx.missingMarkers)
+	if !ok {
+		slog.Warn("skipping hand-written index file without markers", "file", indexFilePath)
+		idx.report.visit(relDir, DirSkipped)
+		idx.skipHandWritten(idx.getRelativePath(indexFilePath))
+		return nil, nil
+	}
+	if exists && string(existing) == content {
+		slog.Debug("index is up to date", "file", indexFilePath)
+		idx.report.visit(relDir, DirUnchanged)
+		return nil, nil
+	}
+
+	change := &Change{
+		Action:  ActionCreate,
+		Path:    idx.getRelativePath(indexFilePath),
+		New:     content,
+		Entries: len(data.Entries),
+		absPath: indexFilePath,
+	}
+	if exists {
+		change.Action = ActionUpdate
+		change.Old = string(existing)
+	}
+	return change, nil
+}
+
+// applyChange writes a planned change to disk, honouring dry run and backup
+// mode
+func (idx *Indexator) applyChange(change *Change) error {
+	indexFilePath := change.absPath
+	idx.writeDiff(change)
+
+	// Handle dry run mode
+	if idx.dryRun {
+		switch change.Action {
+		case ActionUpdate:
+			slog.Info("DRY RUN: Would update index", "file", indexFilePath, "entries", change.Entries)
+		case ActionRemove:
+			slog.Info("DRY RUN: Would remove index", "file", indexFilePath)
+		default:
+			slog.Info("DRY RUN: Would create index", "file", indexFilePath, "entries", change.Entries)
+		}
+		idx.report.applied(change)
+		return nil
+	}
+
+	// Handle backup if file exists
+	if idx.backup {
+		if err := idx.backupExistingFile(indexFilePath); err != nil {
+			slog.Warn("failed to backup existing file", "file", indexFilePath, "error", err)
+		}
+	}
+
+	if change.Action == ActionRemove {
+		// In backup mode the file has already been moved away
+		err := os.Remove(indexFilePath)
+		if err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to remove index file", "file", indexFilePath, "error", err)
+			return fmt.Errorf("failed to remove index file %s: %w", indexFilePath, err)
+		}
+		if err == nil {
+			idx.record(journalRemove, indexFilePath, []byte(change.Old), nil)
+		}
+		slog.Info("Removed index", "file", indexFilePath)
+		return nil
+	}
+
+	// Use atomic file operation to prevent race conditions
+	if change.staged != "" {
+		if err := idx.replaceFile(change.staged, indexFilePath, []byte(change.New)); err != nil {
+			return err
+		}
+	} else if err := idx.writeFileAtomic(indexFilePath, []byte(change.New)); err != nil {
+		return err
+	}
+	idx.report.wrote(indexFilePath, len(change.New))
+	idx.report.applied(change)
+
+	if change.Action == ActionUpdate {
+		slog.Info("Updated index", "file", indexFilePath, "entries", change.Entries)
+	} else {
+		slog.Info("Created index", "file", indexFilePath, "entries", change.Entries)
+	}
+	return nil
+}
+
+// writeDiff writes the unified diff of a change to the diff writer, if any
+func (idx *Indexator) writeDiff(change *Change) {
+	if idx.diffWriter == nil {
+		return
+	}
+
+	oldName, newName := "a/"+change.Path, "b/"+change.Path
+	switch change.Action {
+	case ActionCreate:
+		oldName = diff.DevNull
+	case ActionRemove:
+		newName = diff.DevNull
+	}
+
+	unified := diff.Unified(oldName, newName, change.Old, change.New)
+	if idx.diffColor {
+		unified = diff.Colorize(unified)
+	}
+
+	idx.diffMu.Lock()
+	defer idx.diffMu.Unlock()
+	if _, err := io.WriteString(idx.diffWriter, unified); err != nil {
+		slog.Warn("failed to write diff", "file", change.Path, "error", err)
+	}
+}
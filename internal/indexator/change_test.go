@@ -0,0 +1,37 @@
+package indexator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Start_Diff(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/a.md": "# A",
+		"A/A.md": generatedMarker + "\n[[A/old.md]]\n",
+	})
+
+	var out bytes.Buffer
+	indexator := NewIndexatorWithOptions(tempDir, Options{DryRun: true, Update: true, DiffWriter: &out})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	want := []string{
+		"--- a/A/A.md\n+++ b/A/A.md\n@@ -1,2 +1,2 @@\n " + generatedMarker + "\n-[[A/old.md]]\n+[[A/a.md]]\n",
+		"--- /dev/null\n+++ b/index.md\n@@ -0,0 +1,2 @@\n+" + generatedMarker + "\n+[[A/A.md]]\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(out.String(), w) {
+			t.Errorf("Diff output should contain\n%s\ngot\n%s", w, out.String())
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "index.md")); err == nil {
+		t.Error("Diff in dry run mode should not write files")
+	}
+}
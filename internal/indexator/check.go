@@ -0,0 +1,274 @@
+package indexator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrStale is returned by Check when the index files on disk differ from
+// what a run would generate
+var ErrStale = errors.New("index files are out of date")
+
+// runPlan collects the changes of a run instead of applying them
+type runPlan struct {
+	mu sync.Mutex
+	// preview also passes changes on to applyChange; it is set for dry runs,
+	// where applyChange only logs them
+	preview bool
+	// update regenerates existing index files as in update mode
+	update bool
+	// transaction marks the plan of a transactional run, whose changes are
+	// committed once every directory was indexed
+	transaction bool
+	changes     []Change
+	// created holds the absolute paths of index files that do not exist yet
+	created map[string]bool
+}
+
+func newRunPlan() *runPlan {
+	return &runPlan{
+		created: make(map[string]bool),
+	}
+}
+
+// add records a planned change
+func (p *runPlan) add(change *Change) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, *change)
+	if change.Action == ActionCreate {
+		p.created[change.absPath] = true
+	}
+}
+
+// creates reports whether the plan creates the given index file
+func (p *runPlan) creates(indexPath string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.created[indexPath]
+}
+
+// updating reports whether existing index files are regenerated, either in
+// update mode or because the plan of the run asks for it
+func (idx *Indexator) updating() bool {
+	return idx.update || idx.plan != nil && idx.plan.update
+}
+
+// runIndexes tracks the index files of a run, to tell which generated index
+// files it no longer produces. Only the directories the run indexed are
+// considered: the index files of unchanged, failed, excluded or skipped
+// directories are left alone. Its methods are no-ops on a nil tracker.
+type runIndexes struct {
+	mu sync.Mutex
+	// produced holds the absolute paths of every index file the run
+	// generates, whether or not it changes
+	produced map[string]bool
+	// indexed holds the absolute paths of the directories the run indexed
+	indexed map[string]bool
+	// untouched holds the vault-relative directories whose existing index
+	// files were left as they are outside update mode. They are not
+	// recorded in the manifest, so that update runs regenerate them.
+	untouched map[string]bool
+}
+
+func newRunIndexes() *runIndexes {
+	return &runIndexes{
+		produced:  make(map[string]bool),
+		indexed:   make(map[string]bool),
+		untouched: make(map[string]bool),
+	}
+}
+
+// produce records that the run generates the given index file
+func (r *runIndexes) produce(indexPath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.produced[indexPath] = true
+}
+
+// index records that the run indexed a directory
+func (r *runIndexes) index(dirPath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexed[dirPath] = true
+}
+
+// untouch records that the existing index file of a directory was left as
+// it is
+func (r *runIndexes) untouch(dir string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.untouched[dir] = true
+}
+
+// obsolete reports whether a file is in a directory the run indexed but is
+// not one of the index files it produces, so that it is removed if it is a
+// generated index file
+func (r *runIndexes) obsolete(filePath string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.indexed[filepath.Dir(filePath)] && !r.produced[filePath]
+}
+
+// indexedDirs returns the directories the run indexed, sorted
+func (r *runIndexes) indexedDirs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dirs := make([]string, 0, len(r.indexed))
+	for dirPath := range r.indexed {
+		dirs = append(dirs, dirPath)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// removing reports whether the run also removes the generated index files
+// it no longer produces, which update mode does
+func (idx *Indexator) removing() bool {
+	return idx.updating()
+}
+
+// indexExists reports whether an index file exists, or would exist once the
+// planned changes are applied. Subdirectories are indexed before their
+// parent, so whether their index is produced is known by then.
+func (idx *Indexator) indexExists(indexPath string) bool {
+	if idx.plan.creates(indexPath) {
+		return true
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		return false
+	}
+	if idx.removing() && idx.indexes.obsolete(indexPath) {
+		head, err := readNoteHead(indexPath)
+		return err != nil || !isGenerated(head)
+	}
+	return true
+}
+
+// removeObsolete removes the generated index files the run no longer
+// produces, such as the index of a directory that became empty, or adds
+// their removal to the plan of the run. It returns the removals.
+func (idx *Indexator) removeObsolete() ([]Change, error) {
+	if !idx.removing() {
+		return nil, nil
+	}
+
+	removals, err := idx.findExtraneous()
+	if err != nil {
+		return nil, err
+	}
+	for i := range removals {
+		change := &removals[i]
+		if idx.plan != nil {
+			idx.plan.add(change)
+			if !idx.plan.preview {
+				continue
+			}
+		}
+		if err := idx.applyChange(change); err != nil {
+			return nil, err
+		}
+	}
+	return removals, nil
+}
+
+// Check computes every index in memory, as a run in update mode would, and
+// compares it with the files on disk without writing anything. It returns
+// the index files that are missing or stale, and generated index files that
+// a run would remove, sorted by path. If there are any, the
+// error wraps ErrStale.
+func (idx *Indexator) Check() ([]Change, error) {
+	changes, err := idx.collectChanges(true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range changes {
+		idx.writeDiff(&changes[i])
+	}
+	if len(changes) > 0 {
+		return changes, fmt.Errorf("%w: %d index files differ", ErrStale, len(changes))
+	}
+	return nil, nil
+}
+
+// collectChanges runs the indexer with a plan that collects its changes
+// instead of applying them, ignoring the state of previous runs. With
+// update, or in update mode, existing index files are regenerated and
+// generated index files that are no longer produced are removed. The
+// changes are sorted by path.
+func (idx *Indexator) collectChanges(update bool) ([]Change, error) {
+	idx.plan = newRunPlan()
+	idx.plan.update = update
+	defer func() {
+		idx.plan = nil
+	}()
+
+	if err := idx.Start(); err != nil {
+		return nil, err
+	}
+
+	changes := idx.plan.changes
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// findExtraneous returns removals for the generated index files the run no
+// longer produces in the directories it indexed
+func (idx *Indexator) findExtraneous() ([]Change, error) {
+	var changes []Change
+	for _, dirPath := range idx.indexes.indexedDirs() {
+		entries, err := os.ReadDir(dirPath)
+		if err != nil && idx.keepGoing {
+			idx.skipFailedDir(idx.getRelativePath(dirPath), err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look for extraneous index files: %w", err)
+		}
+		for _, entry := range entries {
+			filePath := filepath.Join(dirPath, entry.Name())
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") || !idx.indexes.obsolete(filePath) {
+				continue
+			}
+			head, err := readNoteHead(filePath)
+			if err != nil || !isGenerated(head) {
+				continue
+			}
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read index file %s: %w", filePath, err)
+			}
+
+			changes = append(changes, Change{
+				Action:  ActionRemove,
+				Path:    idx.getRelativePath(filePath),
+				Old:     string(content),
+				absPath: filePath,
+			})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,171 @@
+package indexator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_Check(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/a.md": "# A",
+		"B/b.md": "# B",
+		"D/d.md": "# D",
+		// Hand-written folder notes are never reported
+		"D/D.md": "My own folder note",
+	})
+
+	if err := NewIndexatorWithOptions(tempDir, Options{Update: true}).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	changes, err := NewIndexator(tempDir).Check()
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Check() on a fresh vault = %v, %v; want no changes", changes, err)
+	}
+
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/new.md": "# New",
+		"C/c.md":   "# C",
+	})
+	if err := os.Remove(filepath.Join(tempDir, "B/b.md")); err != nil {
+		t.Fatalf("Failed to remove note: %v", err)
+	}
+
+	changes, err = NewIndexator(tempDir).Check()
+	if !errors.Is(err, ErrStale) {
+		t.Fatalf("Check() error = %v, want ErrStale", err)
+	}
+
+	want := []struct {
+		path   string
+		action Action
+	}{
+		{"A/A.md", ActionUpdate},
+		{"B/B.md", ActionRemove},
+		{"C/C.md", ActionCreate},
+		{"index.md", ActionUpdate},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Check() returned %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Path != w.path || changes[i].Action != w.action {
+			t.Errorf("change %d = %s %s, want %s %s", i, changes[i].Action, changes[i].Path, w.action, w.path)
+		}
+	}
+
+	// The root index links the planned index of C, but not the removed one
+	// of B
+	if root := changes[3].New; root != generatedMarker+"\n[[A/A.md]]\n[[C/C.md]]\n[[D/D.md]]\n" {
+		t.Errorf("Planned root index = %q", root)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "C/C.md")); err == nil {
+		t.Error("Check() should not write index files")
+	}
+}
+
+func TestIndexator_Start_RemovesObsoleteIndexes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "default", opts: Options{Update: true}},
+		{name: "incremental", opts: Options{Update: true, Incremental: true}},
+		{name: "transaction", opts: Options{Update: true, Transaction: true}},
+		{name: "incremental transaction", opts: Options{Update: true, Incremental: true, Transaction: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeTestFiles(t, tempDir, map[string]string{
+				"A/a.md": "# A",
+				"B/b.md": "# B",
+			})
+			if err := NewIndexatorWithOptions(tempDir, tt.opts).Start(); err != nil {
+				t.Fatalf("first Start() failed: %v", err)
+			}
+
+			// Emptying A leaves its generated index file without notes
+			if err := os.Remove(filepath.Join(tempDir, "A/a.md")); err != nil {
+				t.Fatalf("Failed to remove note: %v", err)
+			}
+			if err := NewIndexatorWithOptions(tempDir, tt.opts).Start(); err != nil {
+				t.Fatalf("second Start() failed: %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(tempDir, "A/A.md")); !os.IsNotExist(err) {
+				t.Errorf("A/A.md should be removed, stat error = %v", err)
+			}
+			root, err := os.ReadFile(filepath.Join(tempDir, "index.md"))
+			if err != nil {
+				t.Fatalf("Failed to read root index: %v", err)
+			}
+			if string(root) != generatedMarker+"\n[[B/B.md]]\n" {
+				t.Errorf("Root index = %q, want only the link to B", root)
+			}
+
+			changes, err := NewIndexator(tempDir).Check()
+			if err != nil || len(changes) != 0 {
+				t.Errorf("Check() after the update = %v, %v; want no changes", changes, err)
+			}
+		})
+	}
+}
+
+func TestIndexator_Start_KeepsIndexesOutsideRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		opts     Options
+		kept     []string
+	}{
+		{
+			name: "excluded",
+			opts: Options{Update: true, ExcludeDirs: []string{"B"}},
+			kept: []string{"B/B.md"},
+		},
+		{
+			name:     "skipped",
+			settings: map[string]string{"Archive/.obsidian-index.yaml": "skip: true\n"},
+			opts:     Options{Update: true},
+			kept:     []string{"Archive/Archive.md", "Archive/old/old.md"},
+		},
+		{
+			name:     "beyond depth",
+			settings: map[string]string{".obsidian-index.yaml": "depth: 1\n"},
+			opts:     Options{Update: true},
+			kept:     []string{"Archive/old/old.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeTestFiles(t, tempDir, map[string]string{
+				"A/a.md":           "# A",
+				"B/b.md":           "# B",
+				"Archive/x.md":     "# X",
+				"Archive/old/y.md": "# Y",
+			})
+			if err := NewIndexatorWithOptions(tempDir, Options{Update: true}).Start(); err != nil {
+				t.Fatalf("first Start() failed: %v", err)
+			}
+
+			writeTestFiles(t, tempDir, tt.settings)
+			if err := NewIndexatorWithOptions(tempDir, tt.opts).Start(); err != nil {
+				t.Fatalf("second Start() failed: %v", err)
+			}
+
+			for _, path := range tt.kept {
+				if _, err := os.Stat(filepath.Join(tempDir, path)); err != nil {
+					t.Errorf("%s should be left alone: %v", path, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package indexator
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// backupSuffix matches the suffix backupExistingFile appends to file names
+var backupSuffix = regexp.MustCompile(`\.backup_\d{8}_\d{6}$`)
+
+// Clean removes every index file generated by the tool, leftover temporary
+// and backup files of index files, and the state and staged files of
+// previous runs. Index files are found by walking the vault and through the
+// directories recorded in the state manifest, but only files carrying the
+// generated marker are removed, so hand-written folder notes are kept even
+// if they contain a managed region. The removals are journaled as a run of
+// their own, so Undo can revert them, and the journals of earlier runs are
+// kept. In dry run mode nothing is removed. It returns the vault-relative
+// paths of the removed files, sorted.
+func (idx *Indexator) Clean() ([]string, error) {
+	found := make(map[string]bool)
+
+	err := filepath.WalkDir(idx.vaultPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && filePath != idx.vaultPath || idx.inBackupStore(idx.getRelativePath(filePath)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if idx.isCleanable(filePath) {
+			found[filePath] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for generated files: %w", err)
+	}
+
+	if state := idx.loadManifest(); state != nil {
+		for dir := range state.Dirs {
+			dirPath := filepath.Join(idx.vaultPath, filepath.FromSlash(dir))
+			indexPath := filepath.Join(dirPath, idx.indexFileName(dirPath))
+			if head, err := readNoteHead(indexPath); err == nil && isGenerated(head) {
+				found[indexPath] = true
+			}
+		}
+	}
+
+	manifestPath := idx.manifestPath()
+	for _, statePath := range []string{manifestPath, manifestPath + ".tmp"} {
+		if _, err := os.Stat(statePath); err == nil {
+			found[statePath] = true
+		}
+	}
+	// Files staged by interrupted transactional runs
+	stagingRoot := filepath.Join(idx.vaultPath, StateDirName, stagingDirName)
+	filepath.WalkDir(stagingRoot, func(filePath string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			found[filePath] = true
+		}
+		return nil
+	})
+
+	paths := make([]string, 0, len(found))
+	for filePath := range found {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	idx.beginRun()
+	removed := make([]string, 0, len(paths))
+	for _, filePath := range paths {
+		if idx.dryRun {
+			slog.Info("DRY RUN: Would remove file", "file", filePath)
+		} else {
+			if err := idx.removeFile(filePath); err != nil {
+				slog.Error("failed to remove file", "file", filePath, "error", err)
+				return removed, fmt.Errorf("failed to remove %s: %w", filePath, err)
+			}
+			slog.Info("Removed file", "file", filePath)
+		}
+		removed = append(removed, idx.getRelativePath(filePath))
+	}
+
+	if !idx.dryRun {
+		idx.endRun()
+		// Only succeed once the directories are empty
+		os.Remove(idx.journalDir())
+		if err := os.RemoveAll(stagingRoot); err != nil {
+			slog.Warn("failed to remove staging directory", "directory", stagingRoot, "error", err)
+		}
+		os.Remove(filepath.Join(idx.vaultPath, StateDirName))
+	}
+	return removed, nil
+}
+
+// removeFile removes a file and records its content in the journal
+func (idx *Indexator) removeFile(filePath string) error {
+	var before []byte
+	if idx.journaling() {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		before = content
+	}
+	if err := os.Remove(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if before != nil {
+		idx.record(journalRemove, filePath, before, nil)
+	}
+	return nil
+}
+
+// isCleanable reports whether Clean removes a file found in the vault: a
+// temporary or backup file left next to the index file of its directory, or
+// a markdown file carrying the generated marker
+func (idx *Indexator) isCleanable(filePath string) bool {
+	name := filepath.Base(filePath)
+	indexName := idx.indexFileName(filepath.Dir(filePath))
+
+	if original, ok := strings.CutSuffix(name, ".tmp"); ok {
+		return original == indexName
+	}
+	if loc := backupSuffix.FindStringIndex(name); loc != nil {
+		return name[:loc[0]] == indexName
+	}
+	if !strings.HasSuffix(name, ".md") && name != indexName {
+		return false
+	}
+
+	head, err := readNoteHead(filePath)
+	return err == nil && isGenerated(head)
+}
@@ -0,0 +1,113 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexator_Clean(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/a.md": "# A",
+		"B/b.md": "# B",
+		// Hand-written folder note with a managed region
+		"B/B.md": "My notes\n" + regionStart + "\n" + regionEnd + "\n",
+		"C/c.md": "# C",
+		// Hand-written folder note without markers
+		"Folder/f.md":      "# F",
+		"Folder/Folder.md": "My own folder note",
+	})
+
+	if err := NewIndexatorWithOptions(tempDir, Options{Update: true, Incremental: true}).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	writeTestFiles(t, tempDir, map[string]string{
+		"C/C.md.tmp":                    generatedMarker + "\n",
+		"C/C.md.backup_20240102_030405": "Old index",
+		// Unrelated files that only look similar
+		"C/notes.tmp":        "scratch",
+		"C/notes.md.tmp":     "scratch note",
+		"C/c.md.backup_copy": "copy",
+	})
+
+	removable := []string{
+		".obsidian-index/state.json",
+		"A/A.md",
+		"C/C.md",
+		"C/C.md.backup_20240102_030405",
+		"C/C.md.tmp",
+		"index.md",
+	}
+
+	removed, err := NewIndexatorWithOptions(tempDir, Options{DryRun: true}).Clean()
+	if err != nil {
+		t.Fatalf("Clean() in dry run mode failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, removable) {
+		t.Errorf("Clean() in dry run mode = %v, want %v", removed, removable)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "index.md")); err != nil {
+		t.Error("Clean() in dry run mode should not remove files")
+	}
+
+	removed, err = NewIndexator(tempDir).Clean()
+	if err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, removable) {
+		t.Errorf("Clean() = %v, want %v", removed, removable)
+	}
+
+	for _, path := range removable {
+		if _, err := os.Stat(filepath.Join(tempDir, path)); err == nil {
+			t.Errorf("Clean() should remove %s", path)
+		}
+	}
+	for _, path := range []string{"B/B.md", "C/notes.tmp", "C/notes.md.tmp", "C/c.md.backup_copy", "A/a.md", "Folder/Folder.md"} {
+		if _, err := os.Stat(filepath.Join(tempDir, path)); err != nil {
+			t.Errorf("Clean() should keep %s", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, StateDirName)); err == nil {
+		t.Error("Clean() should remove the empty state directory")
+	}
+}
+
+func TestIndexator_Clean_Undo(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{"A/a.md": "# A"})
+	if err := NewIndexatorWithOptions(tempDir, Options{Journal: true}).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(tempDir, "A/A.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	if _, err := NewIndexatorWithOptions(tempDir, Options{Journal: true}).Clean(); err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+	runs, err := NewIndexator(tempDir).JournalRuns()
+	if err != nil {
+		t.Fatalf("JournalRuns() failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("Clean() should keep the journal of the run and add its own, got %v", runs)
+	}
+
+	reverted, err := NewIndexator(tempDir).Undo("", false)
+	if err != nil {
+		t.Fatalf("Undo() failed: %v", err)
+	}
+	if want := []string{"A/A.md", "index.md"}; !reflect.DeepEqual(reverted, want) {
+		t.Errorf("Undo() = %v, want %v", reverted, want)
+	}
+	if content, err := os.ReadFile(filepath.Join(tempDir, "A/A.md")); err != nil || string(content) != string(index) {
+		t.Errorf("Undo() should restore the removed index, got %q", content)
+	}
+	if runs, _ := NewIndexator(tempDir).JournalRuns(); len(runs) != 1 {
+		t.Errorf("the run before clean should still be undoable, got %v", runs)
+	}
+}
@@ -0,0 +1,117 @@
+package indexator
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxFrontmatterSize bounds how much of a note is read when looking for
+// frontmatter
+const maxFrontmatterSize = 64 * 1024
+
+// splitFrontmatter splits content into its YAML frontmatter block (including
+// the closing delimiter line) and the remaining text.
+func splitFrontmatter(content string) (frontmatter, rest string) {
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return "", content
+	}
+
+	offset := strings.Index(content, "\n") + 1
+	for offset < len(content) {
+		next := strings.Index(content[offset:], "\n")
+		line := content[offset:]
+		if next >= 0 {
+			line = content[offset : offset+next]
+		}
+		if strings.TrimRight(line, "\r") == "---" {
+			if next < 0 {
+				return content, ""
+			}
+			return content[:offset+next+1], content[offset+next+1:]
+		}
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+
+	return "", content
+}
+
+// readNoteHead reads the beginning of a note, enough to find its
+// frontmatter and first heading
+func readNoteHead(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head, err := io.ReadAll(io.LimitReader(file, maxFrontmatterSize))
+	if err != nil {
+		return "", err
+	}
+	return string(head), nil
+}
+
+// parseFrontmatter parses the YAML frontmatter of content and returns it
+// together with the remaining text. Fields are nil when there is no
+// frontmatter.
+func parseFrontmatter(content string) (map[string]any, string, error) {
+	frontmatter, rest := splitFrontmatter(content)
+	if frontmatter == "" {
+		return nil, content, nil
+	}
+
+	// Drop the opening and closing delimiter lines
+	block := frontmatter[strings.Index(frontmatter, "\n")+1:]
+	block = strings.TrimSuffix(strings.TrimRight(block, "\r\n"), "---")
+
+	var fields map[string]any
+	if err := yaml.Unmarshal([]byte(block), &fields); err != nil {
+		return nil, rest, err
+	}
+	return fields, rest, nil
+}
+
+// readFrontmatter parses the YAML frontmatter at the top of a note. It
+// returns nil without error when the note has no frontmatter.
+func readFrontmatter(filePath string) (map[string]any, error) {
+	head, err := readNoteHead(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, _, err := parseFrontmatter(head)
+	return fields, err
+}
+
+// readTitle returns the display title of a note: the frontmatter title
+// property, or else the text of the first level-one heading. It returns an
+// empty string when the note has neither.
+func readTitle(filePath string) (string, error) {
+	head, err := readNoteHead(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	fields, rest, err := parseFrontmatter(head)
+	if err != nil {
+		// Broken frontmatter should not hide a usable heading
+		fields = nil
+	}
+	if title, ok := fields["title"].(string); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title), nil
+	}
+
+	for _, line := range strings.Split(rest, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:]), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,74 @@
+package indexator
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/nzb3/obsidian-index/internal/ignore"
+)
+
+// gitignoreFileName is the name of the git ignore files read when
+// respectGitignore is set
+const gitignoreFileName = ".gitignore"
+
+// gitignorePatterns returns the .gitignore patterns that apply inside the
+// vault-relative directory relDir: those of the vault root first, then those
+// of each directory down to relDir, so deeper files can override shallower
+// ones with negations
+func (idx *Indexator) gitignorePatterns(relDir string) []ignore.Pattern {
+	idx.gitignoresMu.Lock()
+	defer idx.gitignoresMu.Unlock()
+	return idx.gitignorePatternsLocked(relDir)
+}
+
+// gitignorePatternsLocked is gitignorePatterns for callers holding
+// gitignoresMu
+func (idx *Indexator) gitignorePatternsLocked(relDir string) []ignore.Pattern {
+	relDir = cleanRelPath(relDir)
+	if patterns, ok := idx.gitignores[relDir]; ok {
+		return patterns
+	}
+
+	var patterns []ignore.Pattern
+	if relDir != "." {
+		patterns = append(patterns, idx.gitignorePatternsLocked(path.Dir(relDir))...)
+	}
+	patterns = append(patterns, idx.loadGitignore(relDir)...)
+
+	if idx.gitignores == nil {
+		idx.gitignores = make(map[string][]ignore.Pattern)
+	}
+	idx.gitignores[relDir] = patterns
+	return patterns
+}
+
+// loadGitignore reads the .gitignore file of a single directory. A missing
+// file is not an error; an unreadable or invalid one is logged and ignored.
+func (idx *Indexator) loadGitignore(relDir string) []ignore.Pattern {
+	gitignorePath := filepath.Join(idx.vaultPath, filepath.FromSlash(relDir), gitignoreFileName)
+	content, err := os.ReadFile(gitignorePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read .gitignore", "file", gitignorePath, "error", err)
+		}
+		return nil
+	}
+
+	patterns, err := ignore.ParseLines(string(content), relDir)
+	if err != nil {
+		slog.Warn("ignoring invalid .gitignore", "file", gitignorePath, "error", err)
+		return nil
+	}
+	return patterns
+}
+
+// isGitignored reports whether the .gitignore files of the vault exclude
+// relPath
+func (idx *Indexator) isGitignored(relPath string, isDir bool) bool {
+	if !idx.respectGitignore {
+		return false
+	}
+	return ignore.Excluded(idx.gitignorePatterns(path.Dir(relPath)), relPath, isDir)
+}
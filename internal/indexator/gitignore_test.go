@@ -0,0 +1,61 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_Start_RespectGitignore(t *testing.T) {
+	newVault := func(t *testing.T) string {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			".gitignore":             "build/\n*.pdf\n",
+			"build/out.md":           "# Out",
+			"Notes/.gitignore":       "!keep.pdf\n/scratch\n",
+			"Notes/note.md":          "# Note",
+			"Notes/export.pdf":       "pdf",
+			"Notes/keep.pdf":         "pdf",
+			"Notes/scratch/draft.md": "# Draft",
+			"Other/scratch/idea.md":  "# Idea",
+		})
+		return tempDir
+	}
+
+	t.Run("ignored paths are skipped", func(t *testing.T) {
+		tempDir := newVault(t)
+		indexator := NewIndexatorWithOptions(tempDir, Options{RespectGitignore: true})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		for _, path := range []string{"build/build.md", "Notes/scratch/scratch.md"} {
+			if _, err := os.Stat(filepath.Join(tempDir, path)); err == nil {
+				t.Errorf("%s should not exist for an ignored directory", path)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "Other/scratch/scratch.md")); err != nil {
+			t.Error("Anchored pattern in Notes/.gitignore should not affect Other/scratch")
+		}
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+		if err != nil {
+			t.Fatalf("Failed to read Notes index: %v", err)
+		}
+		expected := generatedMarker + "\n[[Notes/.gitignore]]\n[[Notes/keep.pdf]]\n[[Notes/note.md]]\n"
+		if string(content) != expected {
+			t.Errorf("Notes index = %q, want %q", string(content), expected)
+		}
+	})
+
+	t.Run("gitignore is off by default", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexator(tempDir).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tempDir, "build/build.md")); err != nil {
+			t.Error("build should be indexed when .gitignore is not respected")
+		}
+	})
+}
@@ -2,20 +2,175 @@ package indexator
 
 import (
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+
+	"github.com/nzb3/obsidian-index/internal/ignore"
 )
 
+// ErrPermission matches errors caused by files or directories the indexer
+// may not read or write. It is fs.ErrPermission, so errors.Is matches the
+// errors of package os as well.
+var ErrPermission = fs.ErrPermission
+
 type Indexator struct {
 	vaultPath   string
 	dryRun      bool
 	backup      bool
+	update      bool
 	excludeDirs []string
+	// missingMarkers decides what happens to existing index files without
+	// any markers; see MissingMarkersSkip and friends
+	missingMarkers string
+	templatePath   string
+	tmpl           *template.Template
+	runTime        time.Time
+	sortBy         string
+	sortOverrides  map[string]string
+	foldersFirst   bool
+	group          bool
+	kinds          map[string]string
+	titles         bool
+	// settings caches the effective settings per vault-relative directory
+	settings    map[string]*dirSettings
+	settingsMu  sync.Mutex
+	templates   map[string]*template.Template
+	templatesMu sync.Mutex
+
+	ignoreObsidianExcludes bool
+	obsidianFilters        []obsidianFilter
+	obsidianFiltersLoaded  bool
+	obsidianMu             sync.Mutex
+
+	respectGitignore bool
+	// gitignores caches the .gitignore patterns in effect per
+	// vault-relative directory
+	gitignores   map[string][]ignore.Pattern
+	gitignoresMu sync.Mutex
+
+	incremental bool
+	full        bool
+	// rules caches the hash of the settings files in effect per
+	// vault-relative directory
+	rules   map[string]string
+	rulesMu sync.Mutex
+	// state is the manifest recorded by the last run
+	state *manifest
+
+	debounce time.Duration
+	jobs     int
+	// plan collects changes instead of writing them during Check
+	plan *runPlan
+
+	diffWriter io.Writer
+	diffColor  bool
+	diffMu     sync.Mutex
+
+	backupDir      string
+	backupKeep     int
+	backupKeepDays int
+
+	// runID identifies the current run in the backup store and the
+	// journal; it is picked by the first operation that needs it
+	runID     string
+	runMu     sync.Mutex
+	journal   bool
+	journalMu sync.Mutex
+
+	transaction bool
+	keepGoing   bool
+
+	// report describes the last run started by Start
+	report *runReport
+	// indexes tracks the index files of the current run
+	indexes *runIndexes
+
+	// handWritten are the vault-relative paths of the index files the
+	// current run skipped as hand-written
+	handWritten   []string
+	handWrittenMu sync.Mutex
+
+	// failedDirs are the directories a keep-going run skipped because they
+	// could not be read before indexing
+	failedDirs []*DirError
+}
+
+// Options holds the optional settings of an Indexator
+type Options struct {
+	DryRun         bool
+	Backup         bool
+	Update         bool
+	ExcludeDirs    []string
+	MissingMarkers string
+	// TemplatePath points to a text/template file used to render index
+	// files; the built-in template lists one link per line
+	TemplatePath string
+	// SortBy is the global sort strategy; see SortStrategies
+	SortBy string
+	// SortOverrides maps vault-relative directories to sort strategies.
+	// A directory's strategy is inherited by its subdirectories.
+	SortOverrides map[string]string
+	// FoldersFirst lists subfolders before files
+	FoldersFirst bool
+	// Group splits the built-in index layout into sections by entry kind
+	Group bool
+	// Kinds maps file extensions to entry kinds (notes, canvases or
+	// attachments), on top of the defaults
+	Kinds map[string]string
+	// Titles uses the frontmatter title or first heading of notes as link
+	// aliases and drops the .md extension from link targets
+	Titles bool
+	// IgnoreObsidianExcludes disables the "Excluded files" setting read
+	// from .obsidian/app.json
+	IgnoreObsidianExcludes bool
+	// RespectGitignore excludes paths ignored by the .gitignore files of
+	// the vault
+	RespectGitignore bool
+	// Incremental keeps a manifest of each run in StateDirName and only
+	// regenerates directories that changed since the previous run, along
+	// with their ancestors
+	Incremental bool
+	// Full ignores the manifest of the previous run and rebuilds every
+	// directory; the manifest is still written when Incremental is set
+	Full bool
+	// Debounce is how long Watch waits for the vault to settle before
+	// updating indexes; zero means DefaultDebounce
+	Debounce time.Duration
+	// Jobs is the number of directories indexed concurrently; zero means one
+	Jobs int
+	// DiffWriter receives a unified diff of every index file before it is
+	// created, updated or, by Check, reported
+	DiffWriter io.Writer
+	// DiffColor colourises the diffs written to DiffWriter
+	DiffColor bool
+	// BackupDir is the backup store; empty means backups in StateDirName
+	BackupDir string
+	// BackupKeep is the number of runs kept in the backup store; zero
+	// keeps all of them
+	BackupKeep int
+	// BackupKeepDays is how many days runs are kept in the backup store;
+	// zero keeps them regardless of age
+	BackupKeepDays int
+	// Journal records every file operation of a run in StateDirName, so
+	// that Undo can revert the run
+	Journal bool
+	// Transaction stages the changes of a run and only writes them once
+	// every directory was indexed; if writing fails, the files already
+	// written are restored
+	Transaction bool
+	// KeepGoing carries on indexing the other directories when one fails;
+	// Start then returns an error wrapping ErrPartialFailure that joins the
+	// failure of each directory as a *DirError
+	KeepGoing bool
 }
 
 func NewIndexator(vaultPath string) *Indexator {
@@ -27,43 +182,152 @@ func NewIndexator(vaultPath string) *Indexator {
 	}
 }
 
-func NewIndexatorWithOptions(vaultPath string, dryRun, backup bool, excludeDirs []string) *Indexator {
+func NewIndexatorWithOptions(vaultPath string, opts Options) *Indexator {
+	excludeDirs := opts.ExcludeDirs
+	if excludeDirs == nil {
+		excludeDirs = []string{}
+	}
+
 	return &Indexator{
-		vaultPath:   vaultPath,
-		dryRun:      dryRun,
-		backup:      backup,
-		excludeDirs: excludeDirs,
+		vaultPath:      vaultPath,
+		dryRun:         opts.DryRun,
+		backup:         opts.Backup,
+		update:         opts.Update,
+		excludeDirs:    excludeDirs,
+		missingMarkers: opts.MissingMarkers,
+		templatePath:   opts.TemplatePath,
+		sortBy:         opts.SortBy,
+		sortOverrides:  normalizeSortOverrides(opts.SortOverrides),
+		foldersFirst:   opts.FoldersFirst,
+		group:          opts.Group,
+		kinds:          normalizeKinds(opts.Kinds),
+		titles:         opts.Titles,
+
+		ignoreObsidianExcludes: opts.IgnoreObsidianExcludes,
+		respectGitignore:       opts.RespectGitignore,
+		incremental:            opts.Incremental,
+		full:                   opts.Full,
+		debounce:               opts.Debounce,
+		jobs:                   opts.Jobs,
+		diffWriter:             opts.DiffWriter,
+		diffColor:              opts.DiffColor,
+		backupDir:              opts.BackupDir,
+		backupKeep:             opts.BackupKeep,
+		backupKeepDays:         opts.BackupKeepDays,
+		journal:                opts.Journal,
+		transaction:            opts.Transaction,
+		keepGoing:              opts.KeepGoing,
 	}
 }
 
-// Start begins the indexing process, starting from leaves and moving to root
+// Start begins the indexing process, starting from leaves and moving to root.
+// In keep-going mode directories that fail are skipped and their errors are
+// returned joined once every other directory was indexed. Hand-written index
+// files without markers that the run left alone are listed by
+// HandWrittenFiles.
 func (idx *Indexator) Start() error {
+	// Templates are parsed again, so that a watch rescan picks up edits
+	idx.resetTemplates()
+	if err := idx.loadTemplate(); err != nil {
+		slog.Error("failed to load template", "error", err)
+		return err
+	}
+	idx.beginIndexRun()
+	defer idx.report.end()
+	idx.settings = nil
+	idx.obsidianFiltersLoaded = false
+	idx.gitignores = nil
+	idx.rules = nil
+
+	// Dry runs plan their changes so that parents link the index files
+	// their children would get
+	if idx.dryRun && idx.plan == nil {
+		idx.plan = newRunPlan()
+		idx.plan.preview = true
+		defer func() {
+			idx.plan = nil
+		}()
+	}
+
 	directories, err := idx.CollectDirectories()
 	if err != nil {
 		slog.Error("failed to collect directories", "error", err)
 		return fmt.Errorf("failed to collect directories: %w", err)
 	}
 
-	sort.Slice(directories, func(i, j int) bool {
-		depthI := strings.Count(directories[i], string(filepath.Separator))
-		depthJ := strings.Count(directories[j], string(filepath.Separator))
-		return depthI > depthJ
+	// Deepest directories first, so that parents can link to the index
+	// files of their children
+	sort.SliceStable(directories, func(i, j int) bool {
+		return pathDepth(directories[i]) > pathDepth(directories[j])
 	})
 
-	for _, dir := range directories {
-		if err := idx.indexDirectory(dir); err != nil {
-			slog.Error("failed to index directory", "directory", dir, "error", err)
-			return fmt.Errorf("failed to index directory %s: %w", dir, err)
+	var state *manifest
+	var changed map[string]bool
+	// Checks always compare every directory
+	if idx.incremental && (idx.plan == nil || idx.plan.preview) {
+		state, changed, err = idx.planIncremental(directories)
+		if err != nil {
+			slog.Error("failed to compare with previous run", "error", err)
+			return err
+		}
+	}
+
+	failed := make(map[string]bool)
+	for _, dirErr := range idx.failedDirs {
+		failed[dirErr.Dir] = true
+	}
+	todo := directories
+	if changed != nil || len(failed) > 0 {
+		todo = make([]string, 0, len(directories))
+		for _, dir := range directories {
+			if failed[dir] {
+				continue
+			}
+			if changed != nil && !changed[dir] {
+				slog.Debug("directory unchanged, skipping", "directory", dir)
+				idx.report.visit(dir, DirUnchanged)
+				continue
+			}
+			todo = append(todo, dir)
 		}
 	}
 
-	return nil
+	// In keep-going mode the failed directories are reported once the
+	// others are done
+	indexErr := idx.withFailedDirs(idx.runDirectories(todo, state))
+	if indexErr != nil && !idx.keptGoing(indexErr) {
+		return indexErr
+	}
+
+	if state != nil {
+		// Index files left as they are may be out of date
+		for dir := range idx.indexes.untouched {
+			delete(state.Dirs, dir)
+		}
+	}
+	if state != nil && !idx.dryRun {
+		if err := idx.saveManifest(state); err != nil {
+			return err
+		}
+	}
+	idx.state = state
+
+	if idx.plan == nil {
+		idx.endRun()
+	}
+	return indexErr
 }
 
 func (idx *Indexator) CollectDirectories() ([]string, error) {
+	return idx.collectDirectories(".")
+}
+
+// collectDirectories returns the directories to index below the
+// vault-relative directory root, including root itself
+func (idx *Indexator) collectDirectories(root string) ([]string, error) {
 	var directories []string
 
-	err := fs.WalkDir(os.DirFS(idx.vaultPath), ".", func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(os.DirFS(idx.vaultPath), root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			if os.IsPermission(err) {
 				slog.Warn("permission denied, skipping", "path", path, "error", err)
@@ -73,15 +337,36 @@ func (idx *Indexator) CollectDirectories() ([]string, error) {
 			return err
 		}
 
-		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != "." {
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
 			return filepath.SkipDir
 		}
 
 		if d.IsDir() {
 			// Check if directory should be excluded
-			if idx.shouldExcludeDirectory(path) {
+			if idx.isExcluded(path, true) {
+				idx.report.visit(path, DirExcluded)
+				return filepath.SkipDir
+			}
+
+			settings, err := idx.settingsFor(path)
+			if err != nil && idx.keepGoing {
+				idx.skipFailedDir(path, err)
+				return filepath.SkipDir
+			}
+			if err != nil {
+				slog.Error("invalid directory settings", "path", path, "error", err)
+				return err
+			}
+			if settings.skip {
+				slog.Debug("skipping directory", "path", path)
+				idx.report.visit(path, DirSkipped)
 				return filepath.SkipDir
 			}
+			if settings.maxDepth >= 0 && pathDepth(path) > settings.maxDepth {
+				idx.report.visit(path, DirSkipped)
+				return filepath.SkipDir
+			}
+
 			directories = append(directories, path)
 		}
 
@@ -99,75 +384,110 @@ func (idx *Indexator) indexDirectory(dirPath string) error {
 		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
 	}
 
-	var links []string
+	settings := idx.settingsOrBase(idx.getRelativePath(fullPath))
+
+	data := &IndexData{
+		Name:      idx.dirName(fullPath),
+		Path:      filepath.ToSlash(dirPath),
+		Generated: idx.runTime,
+	}
+	if data.Generated.IsZero() {
+		data.Generated = time.Now()
+	}
+	if fullPath != idx.vaultPath {
+		parentDir := filepath.Dir(fullPath)
+		data.ParentIndex = idx.getRelativePath(filepath.Join(parentDir, idx.indexFileName(parentDir)))
+	}
 
 	for _, entry := range entries {
 		entryPath := filepath.Join(fullPath, entry.Name())
 
-		if idx.isIndexFile(entryPath, fullPath) {
+		if idx.isIndexFile(entryPath, fullPath) || isOverrideFile(entry.Name()) {
+			continue
+		}
+		if fullPath == idx.vaultPath && entry.Name() == vaultConfigFileName {
+			continue
+		}
+		if idx.isExcluded(idx.getRelativePath(entryPath), entry.IsDir()) {
 			continue
 		}
 
 		if entry.IsDir() {
-			indexFileName := entry.Name() + ".md"
-			indexPath := filepath.Join(entryPath, indexFileName)
+			indexPath := filepath.Join(entryPath, idx.indexFileName(entryPath))
 
-			if _, err := os.Stat(indexPath); err == nil {
+			if idx.indexExists(indexPath) {
 				relPath := idx.getRelativePath(indexPath)
-				links = append(links, fmt.Sprintf("[[%s]]", relPath))
+				data.Entries = append(data.Entries, Entry{
+					Name:    entry.Name(),
+					Title:   entry.Name(),
+					Path:    relPath,
+					Link:    settings.formatLink(relPath, ""),
+					IsDir:   true,
+					absPath: indexPath,
+				})
 			}
 		} else {
 			relPath := idx.getRelativePath(entryPath)
-			links = append(links, fmt.Sprintf("[[%s]]", relPath))
+			title := settings.noteTitle(entryPath)
+			data.Entries = append(data.Entries, Entry{
+				Name:    entry.Name(),
+				Title:   title,
+				Path:    relPath,
+				Link:    settings.formatLink(relPath, title),
+				absPath: entryPath,
+			})
 		}
 	}
 
-	if len(links) == 0 {
+	if len(data.Entries) == 0 {
+		idx.report.visit(dirPath, DirSkipped)
 		return nil
 	}
 
-	// Check if index file already exists
-	dirName := filepath.Base(fullPath)
-	if dirName == "." || fullPath == idx.vaultPath {
-		dirName = "index"
-	}
-	indexFileName := dirName + ".md"
-	indexFilePath := filepath.Join(fullPath, indexFileName)
-
-	if _, err := os.Stat(indexFilePath); err == nil {
-		// Index file already exists, skip creation
-		return nil
+	idx.sortEntries(dirPath, data.Entries)
+	for i := range data.Entries {
+		data.Entries[i].Kind = idx.entryKind(data.Entries[i])
 	}
-
-	return idx.createIndexFile(fullPath, links)
-}
-
-func (idx *Indexator) createIndexFile(dirPath string, links []string) error {
-	dirName := filepath.Base(dirPath)
-	if dirName == "." || dirPath == idx.vaultPath {
-		dirName = "index"
+	data.Sections = buildSections(data.Entries)
+	for _, e := range data.Entries {
+		if e.IsDir {
+			data.Folders = append(data.Folders, e)
+		} else {
+			data.Files = append(data.Files, e)
+		}
 	}
 
-	indexFileName := dirName + ".md"
-	indexFilePath := filepath.Join(dirPath, indexFileName)
-
-	content := strings.Join(links, "\n") + "\n"
+	// Check if index file already exists
+	indexFilePath := filepath.Join(fullPath, settings.indexFileName(data.Name))
+	idx.indexes.produce(indexFilePath)
+	idx.report.indexed(dirPath, indexFilePath, idx.getRelativePath(indexFilePath), data)
 
-	// Handle dry run mode
-	if idx.dryRun {
-		slog.Info("DRY RUN: Would create index", "file", indexFilePath, "entries", len(links))
+	if _, err := os.Stat(indexFilePath); err == nil && !idx.updating() {
+		// Index file already exists, skip creation
+		idx.report.visit(dirPath, DirSkipped)
+		idx.indexes.untouch(dirPath)
 		return nil
 	}
 
-	// Handle backup if file exists
-	if idx.backup {
-		if err := idx.backupExistingFile(indexFilePath); err != nil {
-			slog.Warn("failed to backup existing file", "file", indexFilePath, "error", err)
+	change, err := idx.planIndexFile(fullPath, data)
+	if err != nil || change == nil {
+		return err
+	}
+	if idx.plan != nil {
+		idx.plan.add(change)
+		if !idx.plan.preview {
+			return nil
 		}
 	}
+	return idx.applyChange(change)
+}
 
-	// Use atomic file operation to prevent race conditions
-	return idx.writeFileAtomic(indexFilePath, []byte(content))
+func (idx *Indexator) createIndexFile(dirPath string, data *IndexData) error {
+	change, err := idx.planIndexFile(dirPath, data)
+	if err != nil || change == nil {
+		return err
+	}
+	return idx.applyChange(change)
 }
 
 // writeFileAtomic writes content to a file atomically to prevent race conditions
@@ -182,8 +502,20 @@ func (idx *Indexator) writeFileAtomic(filePath string, content []byte) error {
 		return fmt.Errorf("failed to write temporary file %s: %w", tempFile, err)
 	}
 
+	return idx.replaceFile(tempFile, filePath, content)
+}
+
+// replaceFile atomically renames tempFile, which holds content, to filePath
+// and records the write in the journal. tempFile is removed on failure.
+func (idx *Indexator) replaceFile(tempFile, filePath string, content []byte) error {
+	// Keep the previous content for the journal
+	var before []byte
+	if idx.journaling() {
+		before, _ = os.ReadFile(filePath)
+	}
+
 	// Atomic rename operation
-	err = os.Rename(tempFile, filePath)
+	err := os.Rename(tempFile, filePath)
 	if err != nil {
 		// Clean up temporary file on failure
 		os.Remove(tempFile)
@@ -191,10 +523,31 @@ func (idx *Indexator) writeFileAtomic(filePath string, content []byte) error {
 		return fmt.Errorf("failed to rename temporary file %s to %s: %w", tempFile, filePath, err)
 	}
 
-	slog.Info("Created index", "file", filePath, "entries", len(strings.Split(strings.TrimSpace(string(content)), "\n")))
+	if content == nil {
+		content = []byte{}
+	}
+	idx.record(journalWrite, filePath, before, content)
+
+	slog.Debug("Wrote file", "file", filePath, "bytes", len(content))
 	return nil
 }
 
+// dirName returns the name an index file is derived from ("index" for the
+// vault root)
+func (idx *Indexator) dirName(dirPath string) string {
+	dirName := filepath.Base(dirPath)
+	if dirName == "." || dirPath == idx.vaultPath {
+		dirName = "index"
+	}
+	return dirName
+}
+
+// indexFileName returns the index file name of a directory
+func (idx *Indexator) indexFileName(dirPath string) string {
+	settings := idx.settingsOrBase(idx.getRelativePath(dirPath))
+	return settings.indexFileName(idx.dirName(dirPath))
+}
+
 func (idx *Indexator) getRelativePath(absolutePath string) string {
 	relPath, err := filepath.Rel(idx.vaultPath, absolutePath)
 	if err != nil {
@@ -205,50 +558,18 @@ func (idx *Indexator) getRelativePath(absolutePath string) string {
 }
 
 func (idx *Indexator) isIndexFile(filePath, dirPath string) bool {
-	fileName := filepath.Base(filePath)
-	dirName := filepath.Base(dirPath)
-
-	if dirPath == idx.vaultPath {
-		dirName = "index"
-	}
-
-	expectedIndexName := dirName + ".md"
-	return fileName == expectedIndexName
-}
-
-// shouldExcludeDirectory checks if a directory should be excluded from indexing
-func (idx *Indexator) shouldExcludeDirectory(dirPath string) bool {
-	for _, excludeDir := range idx.excludeDirs {
-		// Check if the directory path contains the exclude pattern
-		if strings.Contains(dirPath, excludeDir) {
-			return true
-		}
-		// Check if the directory name matches the exclude pattern
-		dirName := filepath.Base(dirPath)
-		if dirName == excludeDir {
-			return true
-		}
-	}
-	return false
+	return filepath.Base(filePath) == idx.indexFileName(dirPath)
 }
 
-// backupExistingFile creates a backup of an existing file with timestamp
-func (idx *Indexator) backupExistingFile(filePath string) error {
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil // No file to backup
+// isExcluded checks if a vault-relative path should be excluded from
+// indexing. Patterns are gitignore-style; see package ignore.
+func (idx *Indexator) isExcluded(relPath string, isDir bool) bool {
+	relPath = cleanRelPath(relPath)
+	if relPath == "." {
+		return false
 	}
-
-	// Create backup filename with timestamp
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := filePath + ".backup_" + timestamp
-
-	// Copy file to backup location
-	err := os.Rename(filePath, backupPath)
-	if err != nil {
-		return fmt.Errorf("failed to create backup %s: %w", backupPath, err)
+	if idx.isObsidianExcluded(relPath, isDir) || idx.isGitignored(relPath, isDir) || idx.inBackupStore(relPath) {
+		return true
 	}
-
-	slog.Info("Created backup", "original", filePath, "backup", backupPath)
-	return nil
+	return ignore.Excluded(idx.settingsOrBase(path.Dir(relPath)).excludes, relPath, isDir)
 }
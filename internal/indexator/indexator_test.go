@@ -321,7 +321,12 @@ func TestIndexator_createIndexFile(t *testing.T) {
 		t.Fatalf("Failed to create test directory: %v", err)
 	}
 
-	err = indexator.createIndexFile(subDir, links)
+	data := &IndexData{}
+	for _, link := range links {
+		data.Entries = append(data.Entries, Entry{Link: link})
+	}
+
+	err = indexator.createIndexFile(subDir, data)
 	if err != nil {
 		t.Fatalf("createIndexFile() failed: %v", err)
 	}
@@ -346,8 +351,8 @@ func TestIndexator_createIndexFile(t *testing.T) {
 	}
 
 	// Test creating index file in root directory
-	rootLinks := []string{"[[rootfile.md]]"}
-	err = indexator.createIndexFile(tempDir, rootLinks)
+	rootData := &IndexData{Entries: []Entry{{Link: "[[rootfile.md]]"}}}
+	err = indexator.createIndexFile(tempDir, rootData)
 	if err != nil {
 		t.Fatalf("createIndexFile() failed for root: %v", err)
 	}
@@ -479,3 +484,162 @@ func TestIndexator_Start_WithNestedStructure(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexator_Start_UpdateMode(t *testing.T) {
+	tempDir := t.TempDir()
+
+	generatedDir := filepath.Join(tempDir, "generated")
+	handwrittenDir := filepath.Join(tempDir, "handwritten")
+	for _, dir := range []string{generatedDir, handwrittenDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "first.md"), []byte("# First"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	handwrittenIndex := filepath.Join(handwrittenDir, "handwritten.md")
+	if err := os.WriteFile(handwrittenIndex, []byte("# My folder note\n"), 0644); err != nil {
+		t.Fatalf("Failed to create hand-written index file: %v", err)
+	}
+
+	if err := NewIndexator(tempDir).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	// Add a note after the first run
+	for _, dir := range []string{generatedDir, handwrittenDir} {
+		if err := os.WriteFile(filepath.Join(dir, "second.md"), []byte("# Second"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{Update: true})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() in update mode failed: %v", err)
+	}
+	if files := indexator.HandWrittenFiles(); len(files) != 1 || files[0] != "handwritten/handwritten.md" {
+		t.Errorf("HandWrittenFiles() = %v, want [handwritten/handwritten.md]", files)
+	}
+
+	content, err := os.ReadFile(filepath.Join(generatedDir, "generated.md"))
+	if err != nil {
+		t.Fatalf("Failed to read generated index file: %v", err)
+	}
+	if !strings.Contains(string(content), "[[generated/second.md]]") {
+		t.Error("Generated index file should have been regenerated with the new note")
+	}
+
+	content, err = os.ReadFile(handwrittenIndex)
+	if err != nil {
+		t.Fatalf("Failed to read hand-written index file: %v", err)
+	}
+	if string(content) != "# My folder note\n" {
+		t.Errorf("Hand-written index file should not have been modified, got %q", string(content))
+	}
+}
+
+func TestIndexator_Start_UpdateModeWithBackup(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testDir := filepath.Join(tempDir, "testdir")
+	if err := os.MkdirAll(testDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(testDir, "note.md"), []byte("# Note"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := NewIndexator(tempDir).Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(testDir, "other.md"), []byte("# Other"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{Update: true, Backup: true})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(tempDir, StateDirName, "backups", "*", "testdir", "testdir.md"))
+	if err != nil {
+		t.Fatalf("Failed to glob backups: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Errorf("Expected 1 backup file, got %d", len(backups))
+	}
+
+	entries, err := os.ReadDir(testDir)
+	if err != nil {
+		t.Fatalf("Failed to read test directory: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Backups should not be kept next to the index, got %d entries", len(entries))
+	}
+}
+
+func TestIndexator_Start_WithExcludePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+
+	structure := []string{
+		"Smart Notes/idea.md",
+		"Departments/hr.md",
+		"art/sketch.md",
+		"Projects/art/draft.md",
+		"Projects/spec.pdf",
+		"Projects/plan.md",
+		"Templates/daily.md",
+		"Templates/keep/weekly.md",
+	}
+	for _, file := range structure {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("# Content"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{
+		ExcludeDirs: []string{"art", "*.pdf", "/Templates/*", "!/Templates/keep"},
+	})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	for _, indexFile := range []string{
+		"Smart Notes/Smart Notes.md",
+		"Departments/Departments.md",
+		"Projects/Projects.md",
+		"Templates/keep/keep.md",
+	} {
+		if _, err := os.Stat(filepath.Join(tempDir, indexFile)); err != nil {
+			t.Errorf("Expected index file %s was not created", indexFile)
+		}
+	}
+
+	for _, indexFile := range []string{"art/art.md", "Projects/art/art.md"} {
+		if _, err := os.Stat(filepath.Join(tempDir, indexFile)); err == nil {
+			t.Errorf("Excluded directory should not have index file %s", indexFile)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "Projects/Projects.md"))
+	if err != nil {
+		t.Fatalf("Failed to read Projects index: %v", err)
+	}
+	if strings.Contains(string(content), "spec.pdf") {
+		t.Error("Excluded file should not be listed")
+	}
+
+	content, err = os.ReadFile(filepath.Join(tempDir, "Templates/Templates.md"))
+	if err != nil {
+		t.Fatalf("Failed to read Templates index: %v", err)
+	}
+	if string(content) != generatedMarker+"\n[[Templates/keep/keep.md]]\n" {
+		t.Errorf("Templates index should only link the re-included folder, got %q", string(content))
+	}
+}
@@ -0,0 +1,321 @@
+package indexator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// journalDirName is the directory of run journals inside StateDirName
+const journalDirName = "journal"
+
+// journalKeep is the number of run journals kept for Undo
+const journalKeep = 20
+
+// Operations recorded in the journal
+const (
+	journalWrite  = "write"
+	journalBackup = "backup"
+	journalRemove = "remove"
+)
+
+// ErrNothingToUndo is returned by Undo when there is no journal to revert
+var ErrNothingToUndo = errors.New("no run to undo")
+
+// journalEntry records a file operation of a run
+type journalEntry struct {
+	Op string `json:"op"`
+	// Path is the vault-relative path of the file
+	Path string `json:"path"`
+	// OldHash is the SHA-256 of the file before the operation, empty if it
+	// did not exist
+	OldHash string `json:"old_hash,omitempty"`
+	// NewHash is the SHA-256 of the file after the operation, empty if it
+	// no longer exists
+	NewHash string `json:"new_hash,omitempty"`
+	// Old is the content of the file before the operation
+	Old string `json:"old,omitempty"`
+}
+
+// JournalRun is a run recorded in the journal
+type JournalRun struct {
+	ID   string
+	Time time.Time
+	// Files are the vault-relative paths of the files the run touched,
+	// sorted
+	Files []string
+}
+
+// journalDir returns the directory of the run journals
+func (idx *Indexator) journalDir() string {
+	return filepath.Join(idx.vaultPath, StateDirName, journalDirName)
+}
+
+// journalPath returns the journal of a run
+func (idx *Indexator) journalPath(runID string) string {
+	return filepath.Join(idx.journalDir(), runID+".jsonl")
+}
+
+// journaling reports whether file operations are recorded
+func (idx *Indexator) journaling() bool {
+	return idx.journal && !idx.dryRun
+}
+
+// record appends a file operation to the journal of the current run.
+// before is the previous content of the file, or nil if it did not exist;
+// after is its new content, or nil if it no longer exists. Files in the
+// state directory are not recorded. Failures are only logged.
+func (idx *Indexator) record(op, filePath string, before, after []byte) {
+	if !idx.journaling() {
+		return
+	}
+	relPath := idx.getRelativePath(filePath)
+	if relPath == StateDirName || strings.HasPrefix(relPath, StateDirName+"/") {
+		return
+	}
+
+	entry := journalEntry{Op: op, Path: relPath}
+	if before != nil {
+		entry.OldHash = hashBytes(before)
+		entry.Old = string(before)
+	}
+	if after != nil {
+		entry.NewHash = hashBytes(after)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		slog.Warn("failed to encode journal entry", "file", filePath, "error", err)
+		return
+	}
+
+	idx.journalMu.Lock()
+	defer idx.journalMu.Unlock()
+
+	journalPath := idx.journalPath(idx.currentRunID())
+	if err := appendLine(journalPath, line); err != nil {
+		slog.Warn("failed to write journal", "journal", journalPath, "error", err)
+	}
+}
+
+// appendLine appends a line to a file, creating it and its directory if
+// needed
+func appendLine(filePath string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readJournal reads the entries of a run journal. Invalid lines, such as a
+// truncated last line left by an interrupted run, are skipped.
+func (idx *Indexator) readJournal(runID string) ([]journalEntry, error) {
+	journalPath := idx.journalPath(runID)
+	file, err := os.Open(journalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	// Lines hold whole index files
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("skipping invalid journal entry", "journal", journalPath, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal %s: %w", journalPath, err)
+	}
+	return entries, nil
+}
+
+// JournalRuns lists the runs recorded in the journal, newest first
+func (idx *Indexator) JournalRuns() ([]JournalRun, error) {
+	entries, err := os.ReadDir(idx.journalDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal directory: %w", err)
+	}
+
+	var runs []JournalRun
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		runTime, ok := runIDTime(id)
+		if !ok {
+			continue
+		}
+
+		journal, err := idx.readJournal(id)
+		if err != nil {
+			return nil, err
+		}
+		run := JournalRun{ID: id, Time: runTime}
+		seen := make(map[string]bool)
+		for _, e := range journal {
+			if !seen[e.Path] {
+				seen[e.Path] = true
+				run.Files = append(run.Files, e.Path)
+			}
+		}
+		sort.Strings(run.Files)
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return newerRun(runs[i].ID, runs[j].ID)
+	})
+	return runs, nil
+}
+
+// pruneJournals removes the oldest run journals beyond journalKeep
+func (idx *Indexator) pruneJournals() {
+	if !idx.journaling() {
+		return
+	}
+
+	runs, err := idx.JournalRuns()
+	if err != nil {
+		slog.Warn("failed to list journals", "error", err)
+		return
+	}
+	for i := journalKeep; i < len(runs); i++ {
+		if err := os.Remove(idx.journalPath(runs[i].ID)); err != nil {
+			slog.Warn("failed to remove old journal", "run", runs[i].ID, "error", err)
+		}
+	}
+}
+
+// Undo reverts a run recorded in the journal, putting every file it touched
+// back to its previous content and removing the files it created. An empty
+// runID selects the latest run. Files are checked before anything is
+// written: if any of them changed since the run, nothing is reverted and
+// the error wraps ErrConflict, unless force is set. The journal of the run
+// is removed afterwards, so the next Undo reverts the run before it. It
+// returns the vault-relative paths of the reverted files, sorted.
+func (idx *Indexator) Undo(runID string, force bool) ([]string, error) {
+	runs, err := idx.JournalRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	if runID == "" {
+		runID = runs[0].ID
+	} else if !slices.ContainsFunc(runs, func(run JournalRun) bool { return run.ID == runID }) {
+		return nil, fmt.Errorf("%w: no journal for run %s", ErrNothingToUndo, runID)
+	}
+
+	entries, err := idx.readJournal(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first operation on a file holds its content before the run, the
+	// last one its hash after the run
+	first := make(map[string]journalEntry)
+	last := make(map[string]journalEntry)
+	var paths []string
+	for _, entry := range entries {
+		if _, ok := first[entry.Path]; !ok {
+			if entry.OldHash != "" && hashBytes([]byte(entry.Old)) != entry.OldHash {
+				return nil, fmt.Errorf("journal of run %s is corrupt: content of %s does not match its hash", runID, entry.Path)
+			}
+			first[entry.Path] = entry
+			paths = append(paths, entry.Path)
+		}
+		last[entry.Path] = entry
+	}
+	sort.Strings(paths)
+
+	var conflicts []string
+	for _, relPath := range paths {
+		current, err := os.ReadFile(filepath.Join(idx.vaultPath, filepath.FromSlash(relPath)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		currentHash := ""
+		if err == nil {
+			currentHash = hashBytes(current)
+		}
+		if currentHash != last[relPath].NewHash {
+			conflicts = append(conflicts, relPath)
+		}
+	}
+	if len(conflicts) > 0 {
+		if !force {
+			for _, relPath := range conflicts {
+				slog.Error("file changed since the run", "file", relPath, "run", runID)
+			}
+			return nil, fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))
+		}
+		for _, relPath := range conflicts {
+			slog.Warn("reverting file changed since the run", "file", relPath, "run", runID)
+		}
+	}
+
+	if idx.dryRun {
+		for _, relPath := range paths {
+			slog.Info("DRY RUN: Would revert file", "file", relPath, "run", runID)
+		}
+		return paths, nil
+	}
+
+	// Reverting is not itself recorded
+	journal := idx.journal
+	idx.journal = false
+	defer func() {
+		idx.journal = journal
+	}()
+
+	for _, relPath := range paths {
+		target := filepath.Join(idx.vaultPath, filepath.FromSlash(relPath))
+		original := first[relPath]
+		if original.OldHash == "" {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", relPath, err)
+			}
+			slog.Info("Removed file", "file", target, "run", runID)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", relPath, err)
+		}
+		if err := idx.writeFileAtomic(target, []byte(original.Old)); err != nil {
+			return nil, err
+		}
+		slog.Info("Reverted file", "file", target, "run", runID)
+	}
+
+	if err := os.Remove(idx.journalPath(runID)); err != nil {
+		return nil, fmt.Errorf("failed to remove journal of run %s: %w", runID, err)
+	}
+	return paths, nil
+}
@@ -0,0 +1,96 @@
+package indexator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_Undo(t *testing.T) {
+	folderNote := "My notes\n" + regionStart + "\n" + regionEnd + "\n"
+
+	setup := func(t *testing.T) string {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/a.md": "# A",
+			"B/b.md": "# B",
+			"B/B.md": folderNote,
+		})
+		if err := NewIndexatorWithOptions(tempDir, Options{Update: true, Journal: true}).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		return tempDir
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(content)
+	}
+
+	t.Run("undo runs one by one", func(t *testing.T) {
+		tempDir := setup(t)
+		firstIndex := readFile(t, filepath.Join(tempDir, "A/A.md"))
+
+		writeTestFiles(t, tempDir, map[string]string{"A/new.md": "# New"})
+		indexator := NewIndexatorWithOptions(tempDir, Options{Update: true, Backup: true, Journal: true})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		reverted, err := NewIndexator(tempDir).Undo("", false)
+		if err != nil {
+			t.Fatalf("Undo() failed: %v", err)
+		}
+		if len(reverted) != 1 || reverted[0] != "A/A.md" {
+			t.Errorf("Undo() = %v, want [A/A.md]", reverted)
+		}
+		if got := readFile(t, filepath.Join(tempDir, "A/A.md")); got != firstIndex {
+			t.Errorf("Undo() left %q, want %q", got, firstIndex)
+		}
+
+		// Undoing again reverts the first run
+		if _, err := NewIndexator(tempDir).Undo("", false); err != nil {
+			t.Fatalf("second Undo() failed: %v", err)
+		}
+		for _, path := range []string{"A/A.md", "index.md"} {
+			if _, err := os.Stat(filepath.Join(tempDir, path)); err == nil {
+				t.Errorf("Undo() should remove %s created by the run", path)
+			}
+		}
+		if got := readFile(t, filepath.Join(tempDir, "B/B.md")); got != folderNote {
+			t.Errorf("Undo() should restore the folder note, got %q", got)
+		}
+
+		if _, err := NewIndexator(tempDir).Undo("", false); !errors.Is(err, ErrNothingToUndo) {
+			t.Errorf("Undo() without journal error = %v, want ErrNothingToUndo", err)
+		}
+	})
+
+	t.Run("files edited since the run", func(t *testing.T) {
+		tempDir := setup(t)
+		edited := "Edited by hand"
+		writeTestFiles(t, tempDir, map[string]string{"A/A.md": edited})
+
+		_, err := NewIndexator(tempDir).Undo("", false)
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("Undo() error = %v, want ErrConflict", err)
+		}
+		if got := readFile(t, filepath.Join(tempDir, "A/A.md")); got != edited {
+			t.Errorf("Undo() should not revert edited files, got %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "index.md")); err != nil {
+			t.Error("Undo() should not revert anything when a file conflicts")
+		}
+
+		if _, err := NewIndexator(tempDir).Undo("", true); err != nil {
+			t.Fatalf("Undo() with force failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "A/A.md")); err == nil {
+			t.Error("Undo() with force should revert edited files")
+		}
+	})
+}
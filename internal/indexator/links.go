@@ -0,0 +1,46 @@
+package indexator
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// aliasReplacer removes characters that would break a wikilink alias
+var aliasReplacer = strings.NewReplacer("|", "-", "[", "", "]", "")
+
+// formatLink renders a wikilink to relPath. With titles enabled the .md
+// extension is dropped, as Obsidian does, and a non-empty title becomes the
+// link alias.
+func (s *dirSettings) formatLink(relPath, title string) string {
+	if !s.titles {
+		return fmt.Sprintf("[[%s]]", relPath)
+	}
+
+	target := relPath
+	if strings.EqualFold(path.Ext(target), ".md") {
+		target = target[:len(target)-len(".md")]
+	}
+
+	title = strings.TrimSpace(aliasReplacer.Replace(title))
+	if title == "" {
+		return fmt.Sprintf("[[%s]]", target)
+	}
+	return fmt.Sprintf("[[%s|%s]]", target, title)
+}
+
+// noteTitle returns the display title of a note, or an empty string for
+// other files and when titles are disabled
+func (s *dirSettings) noteTitle(filePath string) string {
+	if !s.titles || !strings.EqualFold(path.Ext(filePath), ".md") {
+		return ""
+	}
+
+	title, err := readTitle(filePath)
+	if err != nil {
+		slog.Warn("failed to read note title", "file", filePath, "error", err)
+		return ""
+	}
+	return title
+}
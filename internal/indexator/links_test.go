@@ -0,0 +1,121 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "frontmatter title",
+			content:  "---\ntitle: Meeting notes\ntags: [work]\n---\n# Heading\n",
+			expected: "Meeting notes",
+		},
+		{
+			name:     "first heading",
+			content:  "---\ntags: [work]\n---\nIntro\n\n## Sub\n# Real Title\n",
+			expected: "Real Title",
+		},
+		{
+			name:     "heading without frontmatter",
+			content:  "# Plain\n",
+			expected: "Plain",
+		},
+		{
+			name:     "no title",
+			content:  "Just text\n#tag\n",
+			expected: "",
+		},
+		{
+			name:     "broken frontmatter falls back to heading",
+			content:  "---\ntitle: [unclosed\n---\n# Heading\n",
+			expected: "Heading",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "note.md")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create note: %v", err)
+			}
+
+			title, err := readTitle(filePath)
+			if err != nil {
+				t.Fatalf("readTitle() failed: %v", err)
+			}
+			if title != tt.expected {
+				t.Errorf("readTitle() = %q, want %q", title, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDirSettings_formatLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		titles   bool
+		relPath  string
+		title    string
+		expected string
+	}{
+		{"titles disabled", false, "notes/202401011230.md", "Ignored", "[[notes/202401011230.md]]"},
+		{"note with title", true, "notes/202401011230.md", "My Note", "[[notes/202401011230|My Note]]"},
+		{"note without title", true, "notes/202401011230.md", "", "[[notes/202401011230]]"},
+		{"attachment keeps extension", true, "notes/diagram.pdf", "", "[[notes/diagram.pdf]]"},
+		{"alias is sanitized", true, "a.md", "A | [B]", "[[a|A - B]]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &dirSettings{titles: tt.titles}
+			if got := settings.formatLink(tt.relPath, tt.title); got != tt.expected {
+				t.Errorf("formatLink() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIndexator_Start_WithTitles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"zettel/202401011230.md": "---\ntitle: First idea\n---\nBody",
+		"zettel/202401021000.md": "# Second idea\n",
+		"zettel/sub/note.md":     "text",
+	}
+	for file, content := range files {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{Titles: true})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "zettel/zettel.md"))
+	if err != nil {
+		t.Fatalf("Failed to read zettel index: %v", err)
+	}
+
+	expected := generatedMarker + `
+[[zettel/202401011230|First idea]]
+[[zettel/202401021000|Second idea]]
+[[zettel/sub/sub]]
+`
+	if string(content) != expected {
+		t.Errorf("zettel index = %q, want %q", string(content), expected)
+	}
+}
@@ -0,0 +1,141 @@
+package indexator
+
+import (
+	"sort"
+	"strings"
+)
+
+// generatedMarker is written into every index file produced by the tool so
+// that later runs can tell generated files apart from hand-written notes.
+const generatedMarker = "<!-- obsidian-index:generated -->"
+
+// Region markers delimit the managed block inside a hand-written folder note.
+// Only the text between them is replaced on update.
+const (
+	regionStart = "<!-- obsidian-index:start -->"
+	regionEnd   = "<!-- obsidian-index:end -->"
+)
+
+// Policies for existing index files that carry neither the generated marker
+// nor region markers.
+const (
+	MissingMarkersSkip    = "skip"
+	MissingMarkersAppend  = "append"
+	MissingMarkersPrepend = "prepend"
+)
+
+// IsMissingMarkersPolicy reports whether policy is a supported policy for
+// index files without markers
+func IsMissingMarkersPolicy(policy string) bool {
+	switch policy {
+	case MissingMarkersSkip, MissingMarkersAppend, MissingMarkersPrepend:
+		return true
+	}
+	return false
+}
+
+// skipHandWritten records that the current run left a hand-written index
+// file alone
+func (idx *Indexator) skipHandWritten(relPath string) {
+	idx.handWrittenMu.Lock()
+	defer idx.handWrittenMu.Unlock()
+	idx.handWritten = append(idx.handWritten, relPath)
+}
+
+// HandWrittenFiles returns the vault-relative paths of the hand-written index
+// files without markers that the last run left alone, sorted
+func (idx *Indexator) HandWrittenFiles() []string {
+	idx.handWrittenMu.Lock()
+	defer idx.handWrittenMu.Unlock()
+	files := append([]string(nil), idx.handWritten...)
+	sort.Strings(files)
+	return files
+}
+
+// isGenerated reports whether content was produced by the tool
+func isGenerated(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == generatedMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// withGeneratedMarker inserts the generated marker into body, after the
+// frontmatter if there is one
+func withGeneratedMarker(body string) string {
+	frontmatter, rest := splitFrontmatter(body)
+	return frontmatter + generatedMarker + "\n" + rest
+}
+
+// hasRegion reports whether content contains a start marker followed by an end marker
+func hasRegion(content string) bool {
+	_, _, ok := findRegion(content)
+	return ok
+}
+
+// findRegion returns the offsets of the text between the region markers
+func findRegion(content string) (start, end int, ok bool) {
+	i := strings.Index(content, regionStart)
+	if i < 0 {
+		return 0, 0, false
+	}
+	start = i + len(regionStart)
+
+	j := strings.Index(content[start:], regionEnd)
+	if j < 0 {
+		return 0, 0, false
+	}
+
+	return start, start + j, true
+}
+
+// replaceRegion replaces the managed block of content with body, keeping
+// everything outside the markers byte for byte.
+func replaceRegion(content, body string) string {
+	start, end, ok := findRegion(content)
+	if !ok {
+		return content
+	}
+	return content[:start] + "\n" + body + content[end:]
+}
+
+// managedBlock wraps body in region markers
+func managedBlock(body string) string {
+	return regionStart + "\n" + body + regionEnd + "\n"
+}
+
+// mergeIndexContent computes the new content of an index file from its
+// current content and the generated body. It returns false when the file must
+// be left untouched.
+func mergeIndexContent(existing string, exists bool, body, policy string) (string, bool) {
+	switch {
+	case !exists:
+		return withGeneratedMarker(body), true
+	case hasRegion(existing):
+		return replaceRegion(existing, body), true
+	case isGenerated(existing):
+		return withGeneratedMarker(body), true
+	}
+
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+
+	switch policy {
+	case MissingMarkersAppend:
+		if existing == "" {
+			return managedBlock(body), true
+		}
+		return existing + "\n" + managedBlock(body), true
+	case MissingMarkersPrepend:
+		frontmatter, rest := splitFrontmatter(existing)
+		if rest == "" {
+			return frontmatter + managedBlock(body), true
+		}
+		return frontmatter + managedBlock(body) + "\n" + rest, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,122 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeIndexContent(t *testing.T) {
+	body := "[[a.md]]\n[[b.md]]\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		exists   bool
+		policy   string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "new file",
+			exists:   false,
+			expected: generatedMarker + "\n" + body,
+			ok:       true,
+		},
+		{
+			name:     "generated file",
+			existing: generatedMarker + "\n[[old.md]]\n",
+			exists:   true,
+			expected: generatedMarker + "\n" + body,
+			ok:       true,
+		},
+		{
+			name:     "managed region",
+			existing: "# Intro\n\n" + regionStart + "\n[[old.md]]\n" + regionEnd + "\n\nOutro",
+			exists:   true,
+			expected: "# Intro\n\n" + regionStart + "\n" + body + regionEnd + "\n\nOutro",
+			ok:       true,
+		},
+		{
+			name:     "no markers with skip policy",
+			existing: "# Intro\n",
+			exists:   true,
+			policy:   MissingMarkersSkip,
+			ok:       false,
+		},
+		{
+			name:     "no markers with default policy",
+			existing: "# Intro\n",
+			exists:   true,
+			ok:       false,
+		},
+		{
+			name:     "no markers with append policy",
+			existing: "# Intro",
+			exists:   true,
+			policy:   MissingMarkersAppend,
+			expected: "# Intro\n\n" + regionStart + "\n" + body + regionEnd + "\n",
+			ok:       true,
+		},
+		{
+			name:     "no markers with prepend policy keeps frontmatter first",
+			existing: "---\ntags: [moc]\n---\n# Intro\n",
+			exists:   true,
+			policy:   MissingMarkersPrepend,
+			expected: "---\ntags: [moc]\n---\n" + regionStart + "\n" + body + regionEnd + "\n\n# Intro\n",
+			ok:       true,
+		},
+		{
+			name:     "start marker without end marker",
+			existing: "# Intro\n" + regionStart + "\n",
+			exists:   true,
+			policy:   MissingMarkersSkip,
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := mergeIndexContent(tt.existing, tt.exists, body, tt.policy)
+			if ok != tt.ok {
+				t.Fatalf("mergeIndexContent() ok = %v, want %v", ok, tt.ok)
+			}
+			if result != tt.expected {
+				t.Errorf("mergeIndexContent() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIndexator_Start_UpdatesManagedRegion(t *testing.T) {
+	tempDir := t.TempDir()
+
+	notesDir := filepath.Join(tempDir, "notes")
+	if err := os.MkdirAll(notesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(notesDir, "note.md"), []byte("# Note"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	original := "# Notes\n\nHand-written intro.\n\n" + regionStart + "\n" + regionEnd + "\n\nFooter\n"
+	indexPath := filepath.Join(notesDir, "notes.md")
+	if err := os.WriteFile(indexPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create folder note: %v", err)
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{Update: true})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read folder note: %v", err)
+	}
+
+	expected := "# Notes\n\nHand-written intro.\n\n" + regionStart + "\n[[notes/note.md]]\n" + regionEnd + "\n\nFooter\n"
+	if string(content) != expected {
+		t.Errorf("Folder note = %q, want %q", string(content), expected)
+	}
+}
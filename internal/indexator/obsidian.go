@@ -0,0 +1,98 @@
+package indexator
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// obsidianAppConfig is the part of .obsidian/app.json the Indexator reads
+type obsidianAppConfig struct {
+	UserIgnoreFilters []string `json:"userIgnoreFilters"`
+}
+
+// obsidianFilter is an entry of Obsidian's "Excluded files" setting: either
+// a path prefix or a regular expression written as /regex/
+type obsidianFilter struct {
+	prefix string
+	re     *regexp.Regexp
+}
+
+func (f obsidianFilter) match(relPath string, isDir bool) bool {
+	if f.re != nil {
+		return f.re.MatchString(relPath)
+	}
+	if strings.HasPrefix(relPath, f.prefix) {
+		return true
+	}
+	// A folder filter like "Templates/" also covers the folder itself
+	return isDir && strings.HasPrefix(relPath+"/", f.prefix)
+}
+
+// loadObsidianFilters reads the excluded files configured in Obsidian. A
+// missing or unreadable app.json is not an error.
+func (idx *Indexator) loadObsidianFilters() []obsidianFilter {
+	appConfigPath := filepath.Join(idx.vaultPath, ".obsidian", "app.json")
+	content, err := os.ReadFile(appConfigPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read Obsidian settings", "file", appConfigPath, "error", err)
+		}
+		return nil
+	}
+
+	var appConfig obsidianAppConfig
+	if err := json.Unmarshal(content, &appConfig); err != nil {
+		slog.Warn("failed to parse Obsidian settings", "file", appConfigPath, "error", err)
+		return nil
+	}
+
+	var filters []obsidianFilter
+	for _, raw := range appConfig.UserIgnoreFilters {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if len(raw) > 2 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") {
+			re, err := regexp.Compile(raw[1 : len(raw)-1])
+			if err != nil {
+				slog.Warn("ignoring invalid Obsidian exclude filter", "filter", raw, "error", err)
+				continue
+			}
+			filters = append(filters, obsidianFilter{re: re})
+			continue
+		}
+
+		filters = append(filters, obsidianFilter{prefix: strings.TrimPrefix(raw, "/")})
+	}
+
+	if len(filters) > 0 {
+		slog.Debug("loaded Obsidian exclude filters", "count", len(filters))
+	}
+	return filters
+}
+
+// isObsidianExcluded reports whether Obsidian's own settings exclude relPath
+func (idx *Indexator) isObsidianExcluded(relPath string, isDir bool) bool {
+	if idx.ignoreObsidianExcludes {
+		return false
+	}
+	idx.obsidianMu.Lock()
+	if !idx.obsidianFiltersLoaded {
+		idx.obsidianFilters = idx.loadObsidianFilters()
+		idx.obsidianFiltersLoaded = true
+	}
+	filters := idx.obsidianFilters
+	idx.obsidianMu.Unlock()
+
+	for _, filter := range filters {
+		if filter.match(relPath, isDir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Start_WithObsidianExcludes(t *testing.T) {
+	newVault := func(t *testing.T) string {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			".obsidian/app.json":      `{"userIgnoreFilters": ["Templates/", "Notes/private.md", "/\\.excalidraw$/", "/[/"]}`,
+			"Templates/daily.md":      "# Daily",
+			"Notes/public.md":         "# Public",
+			"Notes/private.md":        "# Private",
+			"Notes/sketch.excalidraw": "{}",
+		})
+		return tempDir
+	}
+
+	t.Run("filters are honoured", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexator(tempDir).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tempDir, "Templates/Templates.md")); err == nil {
+			t.Error("Folder excluded in Obsidian should not have an index file")
+		}
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+		if err != nil {
+			t.Fatalf("Failed to read Notes index: %v", err)
+		}
+		if string(content) != generatedMarker+"\n[[Notes/public.md]]\n" {
+			t.Errorf("Notes index should only list public.md, got %q", string(content))
+		}
+	})
+
+	t.Run("filters can be disabled", func(t *testing.T) {
+		tempDir := newVault(t)
+		indexator := NewIndexatorWithOptions(tempDir, Options{IgnoreObsidianExcludes: true})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tempDir, "Templates/Templates.md")); err != nil {
+			t.Error("Templates should be indexed when Obsidian excludes are disabled")
+		}
+
+		content, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+		if err != nil {
+			t.Fatalf("Failed to read Notes index: %v", err)
+		}
+		if !strings.Contains(string(content), "[[Notes/private.md]]") {
+			t.Error("private.md should be listed when Obsidian excludes are disabled")
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package indexator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// planVersion is bumped whenever the plan format changes incompatibly
+const planVersion = 1
+
+// ErrConflict is returned when index files were edited by hand since a plan
+// was made, by Apply, or since a run wrote them, by Undo. Nothing is written
+// in that case.
+var ErrConflict = errors.New("index files were edited since they were planned or written")
+
+// Plan is a serialisable set of changes to the index files of a vault. It is
+// made by Indexator.Plan and executed by Indexator.Apply.
+type Plan struct {
+	Version int       `json:"version"`
+	Vault   string    `json:"vault"`
+	Created time.Time `json:"created"`
+	// Changes are sorted by path
+	Changes []PlannedChange `json:"changes"`
+}
+
+// PlannedChange is a change to a single index file in a Plan
+type PlannedChange struct {
+	Action Action `json:"action"`
+	// Path is the vault-relative, slash-separated path of the index file
+	Path string `json:"path"`
+	// OldHash is the SHA-256 of the file when the plan was made, empty if
+	// it did not exist
+	OldHash string `json:"old_hash,omitempty"`
+	// NewHash is the SHA-256 of Content, empty when the file is removed
+	NewHash string `json:"new_hash,omitempty"`
+	// Content is the content the file should have
+	Content string `json:"content,omitempty"`
+	// Entries is the number of entries listed in the new index
+	Entries int `json:"entries,omitempty"`
+}
+
+// Plan computes every change a run would make, without writing anything.
+// Unlike a dry run it ignores the state of previous runs, and in update mode
+// it also removes generated index files that are no longer produced, as
+// Check reports them.
+func (idx *Indexator) Plan() (*Plan, error) {
+	changes, err := idx.collectChanges(false)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Version: planVersion,
+		Vault:   idx.vaultPath,
+		Created: time.Now(),
+		Changes: make([]PlannedChange, 0, len(changes)),
+	}
+	for i := range changes {
+		change := &changes[i]
+		idx.writeDiff(change)
+
+		planned := PlannedChange{
+			Action:  change.Action,
+			Path:    change.Path,
+			Content: change.New,
+			Entries: change.Entries,
+		}
+		if change.Action != ActionCreate {
+			planned.OldHash = hashBytes([]byte(change.Old))
+		}
+		if change.Action != ActionRemove {
+			planned.NewHash = hashBytes([]byte(change.New))
+		}
+		plan.Changes = append(plan.Changes, planned)
+	}
+	return plan, nil
+}
+
+// Apply executes a plan, honouring dry run and backup mode. Every file is
+// checked before anything is written: if any of them no longer has the
+// content the plan expects, nothing is applied and the error wraps
+// ErrConflict. With Transaction set the plan is applied as a single
+// transaction.
+func (idx *Indexator) Apply(plan *Plan) error {
+	if err := plan.validate(); err != nil {
+		return err
+	}
+	if plan.Vault != idx.vaultPath {
+		slog.Warn("plan was made for another vault", "plan", plan.Vault, "vault", idx.vaultPath)
+	}
+
+	changes := make([]Change, 0, len(plan.Changes))
+	var conflicts []string
+	for _, planned := range plan.Changes {
+		absPath := filepath.Join(idx.vaultPath, filepath.FromSlash(planned.Path))
+
+		current, err := os.ReadFile(absPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read index file %s: %w", absPath, err)
+		}
+		exists := err == nil
+
+		if exists != (planned.OldHash != "") || exists && hashBytes(current) != planned.OldHash {
+			slog.Error("index file changed since the plan was made", "file", absPath)
+			conflicts = append(conflicts, planned.Path)
+			continue
+		}
+
+		changes = append(changes, Change{
+			Action:  planned.Action,
+			Path:    planned.Path,
+			Old:     string(current),
+			New:     planned.Content,
+			Entries: planned.Entries,
+			absPath: absPath,
+		})
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))
+	}
+
+	idx.beginRun()
+	if idx.transactional() {
+		if err := idx.commitChanges(changes); err != nil {
+			return err
+		}
+	} else {
+		for i := range changes {
+			if err := idx.applyChange(&changes[i]); err != nil {
+				return err
+			}
+		}
+	}
+	idx.endRun()
+	return nil
+}
+
+// ReadPlan reads a plan written by WritePlan
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var plan Plan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// WritePlan writes a plan as indented JSON
+func WritePlan(w io.Writer, plan *Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// Keep the markers in index content readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(plan); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// validate checks that a plan only touches index files inside the vault and
+// that its content matches the recorded hashes
+func (plan *Plan) validate() error {
+	if plan.Version != planVersion {
+		return fmt.Errorf("unsupported plan version %d, want %d", plan.Version, planVersion)
+	}
+
+	for _, planned := range plan.Changes {
+		if !filepath.IsLocal(filepath.FromSlash(planned.Path)) || !strings.HasSuffix(planned.Path, ".md") {
+			return fmt.Errorf("invalid plan: %q is not an index file inside the vault", planned.Path)
+		}
+
+		switch planned.Action {
+		case ActionCreate, ActionUpdate, ActionRemove:
+		default:
+			return fmt.Errorf("invalid plan: unknown action %q for %s", planned.Action, planned.Path)
+		}
+		if (planned.Action == ActionCreate) != (planned.OldHash == "") {
+			return fmt.Errorf("invalid plan: unexpected old hash for %s %s", planned.Action, planned.Path)
+		}
+		if planned.Action != ActionRemove && hashBytes([]byte(planned.Content)) != planned.NewHash {
+			return fmt.Errorf("invalid plan: content of %s does not match its hash", planned.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package indexator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_PlanApply(t *testing.T) {
+	setup := func(t *testing.T) string {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/a.md": "# A",
+			"B/b.md": "# B",
+		})
+		if err := NewIndexatorWithOptions(tempDir, Options{Update: true}).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/new.md": "# New",
+			"C/c.md":   "# C",
+		})
+		if err := os.Remove(filepath.Join(tempDir, "B/b.md")); err != nil {
+			t.Fatalf("Failed to remove note: %v", err)
+		}
+		return tempDir
+	}
+
+	// roundTrip makes a plan in update mode and passes it through its JSON form
+	roundTrip := func(t *testing.T, vault string) *Plan {
+		plan, err := NewIndexatorWithOptions(vault, Options{Update: true}).Plan()
+		if err != nil {
+			t.Fatalf("Plan() failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := WritePlan(&buf, plan); err != nil {
+			t.Fatalf("WritePlan() failed: %v", err)
+		}
+		plan, err = ReadPlan(&buf)
+		if err != nil {
+			t.Fatalf("ReadPlan() failed: %v", err)
+		}
+		return plan
+	}
+
+	t.Run("apply executes the plan", func(t *testing.T) {
+		tempDir := setup(t)
+		plan := roundTrip(t, tempDir)
+
+		want := map[string]Action{
+			"A/A.md":   ActionUpdate,
+			"B/B.md":   ActionRemove,
+			"C/C.md":   ActionCreate,
+			"index.md": ActionUpdate,
+		}
+		if len(plan.Changes) != len(want) {
+			t.Fatalf("Plan() returned %d changes, want %d: %+v", len(plan.Changes), len(want), plan.Changes)
+		}
+		for _, change := range plan.Changes {
+			if want[change.Path] != change.Action {
+				t.Errorf("Planned %s %s, want %s", change.Action, change.Path, want[change.Path])
+			}
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "C/C.md")); err == nil {
+			t.Fatal("Plan() should not write index files")
+		}
+
+		if err := NewIndexator(tempDir).Apply(plan); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		if changes, err := NewIndexator(tempDir).Check(); err != nil {
+			t.Errorf("Check() after Apply() = %v, %v; want no changes", changes, err)
+		}
+	})
+
+	t.Run("apply refuses files changed since planning", func(t *testing.T) {
+		tempDir := setup(t)
+		plan := roundTrip(t, tempDir)
+
+		rootIndex := filepath.Join(tempDir, "index.md")
+		edited := generatedMarker + "\nEdited by someone else\n"
+		writeTestFiles(t, tempDir, map[string]string{
+			"index.md": edited,
+			// Created by someone else after planning
+			"C/C.md": generatedMarker + "\n",
+		})
+
+		err := NewIndexator(tempDir).Apply(plan)
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("Apply() error = %v, want ErrConflict", err)
+		}
+
+		if content, _ := os.ReadFile(rootIndex); string(content) != edited {
+			t.Errorf("Apply() overwrote a conflicting file: %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "B/B.md")); err != nil {
+			t.Error("Apply() should not apply any change of a conflicting plan")
+		}
+	})
+
+	t.Run("invalid plans are rejected", func(t *testing.T) {
+		tempDir := setup(t)
+		plan := roundTrip(t, tempDir)
+
+		plan.Changes[0].Path = "../outside.md"
+		if err := NewIndexator(tempDir).Apply(plan); err == nil {
+			t.Error("Apply() should reject paths outside the vault")
+		}
+	})
+}
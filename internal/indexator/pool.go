@@ -0,0 +1,238 @@
+package indexator
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DirError is the failure to index a single directory
+type DirError struct {
+	// Dir is the vault-relative directory
+	Dir string
+	Err error
+}
+
+func (e *DirError) Error() string {
+	return fmt.Sprintf("failed to index directory %s: %v", e.Dir, e.Err)
+}
+
+func (e *DirError) Unwrap() error {
+	return e.Err
+}
+
+// ErrPartialFailure is returned by keep-going runs when some directories
+// could not be indexed; the failures are joined in the error as *DirError
+var ErrPartialFailure = errors.New("some directories could not be indexed")
+
+// DirErrors returns the failures of the directories a keep-going run could
+// not index, or nil if err does not wrap ErrPartialFailure
+func DirErrors(err error) []*DirError {
+	if !errors.Is(err, ErrPartialFailure) {
+		return nil
+	}
+
+	var dirErrs []*DirError
+	var collect func(error)
+	collect = func(err error) {
+		switch e := err.(type) {
+		case *DirError:
+			dirErrs = append(dirErrs, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				collect(inner)
+			}
+		case interface{ Unwrap() error }:
+			collect(e.Unwrap())
+		}
+	}
+	collect(err)
+	return dirErrs
+}
+
+// keptGoing reports whether a keep-going run carries on after err, because
+// it only holds failures of single directories
+func (idx *Indexator) keptGoing(err error) bool {
+	return idx.keepGoing && errors.Is(err, ErrPartialFailure)
+}
+
+// dirResult is the outcome of indexing a single directory
+type dirResult struct {
+	dir   string
+	state dirState
+	err   *DirError
+}
+
+// indexDirectories indexes the given vault-relative directories with a pool
+// of idx.jobs workers. A directory is scheduled as soon as all of its
+// children in the list are done, because its index links to their index
+// files. Each worker reads at most one directory at a time, which bounds the
+// number of open files. When state is not nil the new state of each
+// directory is recorded in it.
+//
+// The generated files do not depend on the number of workers. The first
+// error stops scheduling; once the running workers have finished, the
+// failure of the directory with the lowest path is returned, so that it does
+// not depend on which worker failed first. In keep-going mode a failed
+// directory counts as done instead, and the failures of every directory are
+// returned joined in path order, wrapping ErrPartialFailure. Failed
+// directories are removed from state, so the next run retries them.
+func (idx *Indexator) indexDirectories(directories []string, state *manifest) error {
+	jobs := idx.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	// pendingChildren counts the children of each directory that still have
+	// to be indexed
+	pendingChildren := make(map[string]int, len(directories))
+	for _, dir := range directories {
+		pendingChildren[dir] = 0
+	}
+	for _, dir := range directories {
+		if parent, ok := parentDir(dir); ok {
+			if _, scheduled := pendingChildren[parent]; scheduled {
+				pendingChildren[parent]++
+			}
+		}
+	}
+
+	var ready []string
+	for _, dir := range directories {
+		if pendingChildren[dir] == 0 {
+			ready = append(ready, dir)
+		}
+	}
+
+	work := make(chan string)
+	results := make(chan dirResult)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			for dir := range work {
+				results <- idx.indexAndMeasure(dir, state != nil)
+			}
+		}()
+	}
+	defer close(work)
+
+	var errs []*DirError
+	running := 0
+	for len(ready) > 0 || running > 0 {
+		// A nil channel blocks, so nothing new is scheduled after an error
+		var next chan string
+		var dir string
+		if len(ready) > 0 && (len(errs) == 0 || idx.keepGoing) {
+			next = work
+			dir = ready[0]
+		}
+		if next == nil && running == 0 {
+			break
+		}
+
+		select {
+		case next <- dir:
+			ready = ready[1:]
+			running++
+
+		case result := <-results:
+			running--
+			if result.err != nil {
+				errs = append(errs, result.err)
+				if state != nil {
+					delete(state.Dirs, result.dir)
+				}
+				if !idx.keepGoing {
+					continue
+				}
+			} else if state != nil {
+				state.Dirs[result.dir] = result.state
+			}
+
+			if parent, ok := parentDir(result.dir); ok {
+				if _, scheduled := pendingChildren[parent]; scheduled {
+					pendingChildren[parent]--
+					if pendingChildren[parent] == 0 {
+						ready = append(ready, parent)
+					}
+				}
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if !idx.keepGoing {
+		// Workers finish in any order
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Dir < errs[j].Dir
+		})
+		return errs[0]
+	}
+	return partialFailure(errs)
+}
+
+// partialFailure joins the failures of single directories in path order,
+// wrapping ErrPartialFailure
+func partialFailure(errs []*DirError) error {
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Dir < errs[j].Dir
+	})
+	joined := make([]error, len(errs))
+	for i, err := range errs {
+		joined[i] = err
+	}
+	return fmt.Errorf("%w: %w", ErrPartialFailure, errors.Join(joined...))
+}
+
+// skipFailedDir records that a keep-going run skips a directory, and the
+// subtree below it, because it could not be read before indexing
+func (idx *Indexator) skipFailedDir(dir string, err error) {
+	slog.Error("failed to read directory, skipping", "directory", dir, "error", err)
+	idx.report.done(dir, 0, err)
+	idx.failedDirs = append(idx.failedDirs, &DirError{Dir: dir, Err: err})
+}
+
+// withFailedDirs adds the directories skipped by skipFailedDir to the
+// outcome of indexing, unless indexing failed as a whole
+func (idx *Indexator) withFailedDirs(indexErr error) error {
+	if len(idx.failedDirs) == 0 || indexErr != nil && !idx.keptGoing(indexErr) {
+		return indexErr
+	}
+	return partialFailure(append(DirErrors(indexErr), idx.failedDirs...))
+}
+
+// indexAndMeasure indexes a directory and, if record is set, computes its
+// new state
+func (idx *Indexator) indexAndMeasure(dir string, record bool) dirResult {
+	started := time.Now()
+	err := idx.indexDirectory(dir)
+	idx.report.done(dir, time.Since(started), err)
+	if err != nil {
+		slog.Error("failed to index directory", "directory", dir, "error", err)
+		return dirResult{dir: dir, err: &DirError{Dir: dir, Err: err}}
+	}
+	idx.indexes.index(filepath.Join(idx.vaultPath, dir))
+	if !record {
+		return dirResult{dir: dir}
+	}
+
+	state, err := idx.dirState(dir)
+	if err != nil {
+		return dirResult{dir: dir, err: &DirError{Dir: dir, Err: err}}
+	}
+	return dirResult{dir: dir, state: state}
+}
+
+// parentDir returns the parent of a vault-relative directory, or false for
+// the vault root
+func parentDir(dir string) (string, bool) {
+	if dir == "." {
+		return "", false
+	}
+	return path.Dir(dir), true
+}
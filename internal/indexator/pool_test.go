@@ -0,0 +1,216 @@
+package indexator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Start_Jobs(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 4; j++ {
+			files[fmt.Sprintf("Area%d/Project%d/Notes/note.md", i, j)] = "# Note"
+			files[fmt.Sprintf("Area%d/Project%d/readme.md", i, j)] = "# Readme"
+		}
+		files[fmt.Sprintf("Area%d/overview.md", i)] = "# Overview"
+	}
+
+	// indexVault indexes a fresh copy of the vault and returns the generated
+	// index files by path
+	indexVault := func(t *testing.T, jobs int) map[string]string {
+		t.Helper()
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, files)
+
+		indexator := NewIndexatorWithOptions(tempDir, Options{Jobs: jobs, Titles: true})
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() with %d jobs failed: %v", jobs, err)
+		}
+
+		indexes := make(map[string]string)
+		err := filepath.WalkDir(tempDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(tempDir, path)
+			if _, isNote := files[filepath.ToSlash(rel)]; isNote {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			indexes[filepath.ToSlash(rel)] = string(content)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to read indexes: %v", err)
+		}
+		return indexes
+	}
+
+	sequential := indexVault(t, 1)
+	parallel := indexVault(t, 8)
+
+	if len(sequential) != len(parallel) {
+		t.Fatalf("Got %d index files with 8 jobs, want %d", len(parallel), len(sequential))
+	}
+	for path, want := range sequential {
+		if got := parallel[path]; got != want {
+			t.Errorf("%s with 8 jobs = %q, want %q", path, got, want)
+		}
+	}
+
+	root := parallel["index.md"]
+	for i := 0; i < 5; i++ {
+		if link := fmt.Sprintf("[[Area%d/Area%d]]", i, i); !strings.Contains(root, link) {
+			t.Errorf("Root index should link %s after its children were indexed, got %q", link, root)
+		}
+	}
+}
+
+func TestIndexator_Start_KeepGoing(t *testing.T) {
+	// Rendering fails for B only
+	templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+	writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+		"index.tmpl": `{{if eq .Name "B"}}{{.Missing}}{{end}}{{range .Entries}}{{.Link}}{{end}}`,
+	})
+
+	for _, transaction := range []bool{false, true} {
+		t.Run(fmt.Sprintf("transaction=%t", transaction), func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeTestFiles(t, tempDir, map[string]string{
+				"A/a.md": "# A",
+				"B/b.md": "# B",
+				"C/c.md": "# C",
+			})
+
+			indexator := NewIndexatorWithOptions(tempDir, Options{
+				TemplatePath: templatePath,
+				Incremental:  true,
+				Transaction:  transaction,
+				KeepGoing:    true,
+			})
+			err := indexator.Start()
+			failed := DirErrors(err)
+			if len(failed) != 1 || failed[0].Dir != "B" {
+				t.Fatalf("Start() error = %v, want a single failure of B", err)
+			}
+
+			for _, path := range []string{"A/A.md", "C/C.md", "index.md"} {
+				if _, err := os.Stat(filepath.Join(tempDir, path)); err != nil {
+					t.Errorf("Start() should keep going and write %s", path)
+				}
+			}
+			state := indexator.loadManifest()
+			if state == nil {
+				t.Fatal("Start() should record the state of the run")
+			}
+			if _, ok := state.Dirs["B"]; ok {
+				t.Error("failed directory should not be recorded, so that the next run retries it")
+			}
+		})
+	}
+}
+
+func TestIndexator_Start_KeepGoingUnreadable(t *testing.T) {
+	tests := []struct {
+		name string
+		// breakB makes directory B unreadable
+		breakB   func(t *testing.T, dirPath string)
+		needUser bool
+	}{
+		{
+			name: "settings file",
+			breakB: func(t *testing.T, dirPath string) {
+				// Reading a directory fails even for root
+				if err := os.Mkdir(filepath.Join(dirPath, ".obsidian-index.yaml"), 0755); err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+			},
+		},
+		{
+			name:     "no permissions",
+			breakB:   func(t *testing.T, dirPath string) { chmod(t, dirPath, 0000) },
+			needUser: true,
+		},
+		{
+			name:     "listing not permitted",
+			breakB:   func(t *testing.T, dirPath string) { chmod(t, dirPath, 0311) },
+			needUser: true,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, opts := range []Options{
+			{Update: true, KeepGoing: true},
+			{Update: true, KeepGoing: true, Incremental: true},
+			{Update: true, KeepGoing: true, Incremental: true, Full: true},
+		} {
+			t.Run(fmt.Sprintf("%s incremental=%t full=%t", tt.name, opts.Incremental, opts.Full), func(t *testing.T) {
+				if tt.needUser && os.Geteuid() == 0 {
+					t.Skip("permissions do not apply to root")
+				}
+				tempDir := t.TempDir()
+				writeTestFiles(t, tempDir, map[string]string{
+					"A/a.md":     "# A",
+					"B/b.md":     "# B",
+					"B/sub/s.md": "# S",
+				})
+				tt.breakB(t, filepath.Join(tempDir, "B"))
+
+				err := NewIndexatorWithOptions(tempDir, opts).Start()
+				if !errors.Is(err, ErrPartialFailure) {
+					t.Fatalf("Start() error = %v, want ErrPartialFailure", err)
+				}
+				if failed := DirErrors(err); len(failed) != 1 || failed[0].Dir != "B" {
+					t.Fatalf("Start() error = %v, want a single failure of B", err)
+				}
+				for _, path := range []string{"A/A.md", "index.md"} {
+					if _, err := os.Stat(filepath.Join(tempDir, path)); err != nil {
+						t.Errorf("Start() should keep going and write %s", path)
+					}
+				}
+			})
+		}
+	}
+}
+
+// chmod changes the mode of a directory and restores it once the test is
+// done, so that it can be removed
+func chmod(t *testing.T, dirPath string, mode os.FileMode) {
+	t.Helper()
+	if err := os.Chmod(dirPath, mode); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(dirPath, 0755) })
+}
+
+func TestIndexator_Start_JobsError(t *testing.T) {
+	// Rendering fails for every directory
+	templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+	writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+		"index.tmpl": `{{.Missing}}`,
+	})
+	files := make(map[string]string)
+	for _, dir := range []string{"A", "B", "C", "D", "E", "F"} {
+		files[dir+"/note.md"] = "# Note"
+	}
+
+	for i := 0; i < 10; i++ {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, files)
+
+		indexator := NewIndexatorWithOptions(tempDir, Options{TemplatePath: templatePath, Jobs: 4})
+		err := indexator.Start()
+		var dirErr *DirError
+		if !errors.As(err, &dirErr) || dirErr.Dir != "A" {
+			t.Fatalf("Start() error = %v, want the failure of A whichever worker fails first", err)
+		}
+	}
+}
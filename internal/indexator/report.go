@@ -0,0 +1,260 @@
+package indexator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DirAction is what a run did with a directory
+type DirAction string
+
+// Actions on directories
+const (
+	// DirCreated and DirUpdated mean the index file was created or
+	// rewritten, or would be in a dry run
+	DirCreated DirAction = "created"
+	DirUpdated DirAction = "updated"
+	// DirUnchanged means the index file is up to date, or the directory did
+	// not change since the previous run
+	DirUnchanged DirAction = "unchanged"
+	// DirSkipped means the directory gets no index file: it is empty, its
+	// index file exists outside update mode or is hand-written, or its
+	// settings skip it
+	DirSkipped  DirAction = "skipped"
+	DirExcluded DirAction = "excluded"
+	DirFailed   DirAction = "failed"
+)
+
+// Report describes a run directory by directory. It is made by
+// Indexator.Report and written by WriteReport.
+type Report struct {
+	Vault      string    `json:"vault"`
+	Started    time.Time `json:"started"`
+	DurationMS float64   `json:"duration_ms"`
+	DryRun     bool      `json:"dry_run"`
+	// Summary counts the directories by action
+	Summary map[DirAction]int `json:"summary"`
+	// Dirs are sorted by path
+	Dirs []DirReport `json:"directories"`
+}
+
+// DirReport describes what a run did with a single directory
+type DirReport struct {
+	// Path is the vault-relative, slash-separated path of the directory
+	Path   string    `json:"path"`
+	Action DirAction `json:"action"`
+	// Index is the vault-relative path of the index file of the directory
+	Index   string `json:"index,omitempty"`
+	Entries int    `json:"entries"`
+	Folders int    `json:"folders"`
+	Files   int    `json:"files"`
+	// BytesWritten is the size of the index file written by the run
+	BytesWritten int `json:"bytes_written"`
+	// Backups are the backups of the previous index file, relative to the
+	// vault when the backup store is inside it
+	Backups    []string `json:"backups,omitempty"`
+	DurationMS float64  `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// runReport collects the report of a run. Its methods are no-ops on a nil
+// report.
+type runReport struct {
+	mu       sync.Mutex
+	started  time.Time
+	duration time.Duration
+	dirs     map[string]*DirReport
+	// indexDirs maps the absolute paths of index files to their directory
+	indexDirs map[string]string
+}
+
+func newRunReport(started time.Time) *runReport {
+	return &runReport{
+		started:   started,
+		dirs:      make(map[string]*DirReport),
+		indexDirs: make(map[string]string),
+	}
+}
+
+// dirLocked returns the report of a vault-relative directory, creating it.
+// The caller holds mu.
+func (r *runReport) dirLocked(dir string) *DirReport {
+	dir = cleanRelPath(dir)
+	report, ok := r.dirs[dir]
+	if !ok {
+		report = &DirReport{Path: dir}
+		r.dirs[dir] = report
+	}
+	return report
+}
+
+// visit records what the run did with a directory
+func (r *runReport) visit(dir string, action DirAction) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirLocked(dir).Action = action
+}
+
+// indexed records the index file and the entries of a directory
+func (r *runReport) indexed(dir, indexPath, relIndexPath string, data *IndexData) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.dirLocked(dir)
+	report.Index = relIndexPath
+	report.Entries = len(data.Entries)
+	report.Folders = len(data.Folders)
+	report.Files = len(data.Files)
+	r.indexDirs[indexPath] = report.Path
+}
+
+// wrote records the bytes written to an index file
+func (r *runReport) wrote(indexPath string, n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if dir, ok := r.indexDirs[indexPath]; ok {
+		r.dirs[dir].BytesWritten += n
+	}
+}
+
+// applied records that a change to an index file was made, or would be in a
+// dry run. Removals leave the action of the directory as it is.
+func (r *runReport) applied(change *Change) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dir, ok := r.indexDirs[change.absPath]
+	if !ok {
+		return
+	}
+	switch change.Action {
+	case ActionCreate:
+		r.dirs[dir].Action = DirCreated
+	case ActionUpdate:
+		r.dirs[dir].Action = DirUpdated
+	}
+}
+
+// abandoned records that the changes of a run were not made, or were rolled
+// back, because of err: their directories failed
+func (r *runReport) abandoned(changes []Change, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, change := range changes {
+		dir, ok := r.indexDirs[change.absPath]
+		if !ok || r.dirs[dir].Action == DirFailed {
+			continue
+		}
+		r.dirs[dir].Action = DirFailed
+		r.dirs[dir].BytesWritten = 0
+		r.dirs[dir].Error = err.Error()
+	}
+}
+
+// backedUp records the backup of an index file
+func (r *runReport) backedUp(indexPath, backupPath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if dir, ok := r.indexDirs[indexPath]; ok {
+		r.dirs[dir].Backups = append(r.dirs[dir].Backups, backupPath)
+	}
+}
+
+// done records how long indexing a directory took and why it failed, if it
+// did
+func (r *runReport) done(dir string, duration time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.dirLocked(dir)
+	report.DurationMS = milliseconds(duration)
+	if err != nil {
+		report.Action = DirFailed
+		report.Error = err.Error()
+	}
+}
+
+// end records the end of the run
+func (r *runReport) end() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.duration = time.Since(r.started)
+}
+
+// Report returns the report of the last run started by Start, or nil if
+// there was none
+func (idx *Indexator) Report() *Report {
+	r := idx.report
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		Vault:      idx.vaultPath,
+		Started:    r.started,
+		DurationMS: milliseconds(r.duration),
+		DryRun:     idx.dryRun,
+		Summary:    make(map[DirAction]int),
+		Dirs:       make([]DirReport, 0, len(r.dirs)),
+	}
+	for _, dir := range r.dirs {
+		report.Summary[dir.Action]++
+		report.Dirs = append(report.Dirs, *dir)
+	}
+	sort.Slice(report.Dirs, func(i, j int) bool {
+		return report.Dirs[i].Path < report.Dirs[j].Path
+	})
+	return report
+}
+
+// WriteReport writes a report as indented JSON
+func WriteReport(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// reportPath returns how a file is shown in reports: relative to the vault
+// if it is inside it, absolute otherwise
+func (idx *Indexator) reportPath(filePath string) string {
+	if relPath, err := filepath.Rel(idx.vaultPath, filePath); err == nil && filepath.IsLocal(relPath) {
+		return filepath.ToSlash(relPath)
+	}
+	return filePath
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,143 @@
+package indexator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Report(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"A/a.md": "# A",
+		"C/c.md": "# C",
+		"D/d.md": "# D",
+		"D/D.md": "Hand-written folder note",
+	})
+	if err := os.Mkdir(filepath.Join(tempDir, "B"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// dirs runs the indexator and returns the report by directory
+	dirs := func(t *testing.T, opts Options) map[string]DirReport {
+		t.Helper()
+		opts.ExcludeDirs = []string{"C"}
+		indexator := NewIndexatorWithOptions(tempDir, opts)
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		report := indexator.Report()
+		if report == nil {
+			t.Fatal("Report() returned nil after a run")
+		}
+		byPath := make(map[string]DirReport)
+		for _, dir := range report.Dirs {
+			byPath[dir.Path] = dir
+		}
+		return byPath
+	}
+
+	first := dirs(t, Options{Update: true})
+	want := map[string]DirAction{
+		".": DirCreated,
+		"A": DirCreated,
+		"B": DirSkipped,
+		"C": DirExcluded,
+		"D": DirSkipped,
+	}
+	for path, action := range want {
+		if got := first[path].Action; got != action {
+			t.Errorf("first run: %s action = %q, want %q", path, got, action)
+		}
+	}
+	indexA, err := os.ReadFile(filepath.Join(tempDir, "A/A.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if a := first["A"]; a.Index != "A/A.md" || a.Entries != 1 || a.Files != 1 || a.BytesWritten != len(indexA) {
+		t.Errorf("first run: A = %+v, want index A/A.md with 1 file and %d bytes written", a, len(indexA))
+	}
+
+	writeTestFiles(t, tempDir, map[string]string{"A/new.md": "# New"})
+	second := dirs(t, Options{Update: true, Backup: true})
+	if got := second["."].Action; got != DirUnchanged {
+		t.Errorf("second run: root action = %q, want %q", got, DirUnchanged)
+	}
+	a := second["A"]
+	if a.Action != DirUpdated || a.Entries != 2 {
+		t.Errorf("second run: A = %+v, want updated with 2 entries", a)
+	}
+	if len(a.Backups) != 1 || !strings.HasPrefix(a.Backups[0], StateDirName+"/backups/") {
+		t.Errorf("second run: A backups = %v, want one in the backup store", a.Backups)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, &Report{Dirs: []DirReport{a}}); err != nil {
+		t.Fatalf("WriteReport() failed: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteReport() wrote invalid JSON: %v", err)
+	}
+	if len(decoded.Dirs) != 1 || decoded.Dirs[0].Action != DirUpdated {
+		t.Errorf("WriteReport() round trip = %+v", decoded)
+	}
+}
+
+func TestIndexator_Report_FailedTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepGoing bool
+		wantA     DirAction
+	}{
+		// The change planned for A is abandoned with the run
+		{name: "failing directory", wantA: DirFailed},
+		{name: "keep going", keepGoing: true, wantA: DirCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeTestFiles(t, tempDir, map[string]string{
+				"A/a.md": "# A",
+				"B/b.md": "# B",
+			})
+			// Rendering fails for B only
+			templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+			writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+				"index.tmpl": `{{if eq .Name "B"}}{{.Missing}}{{end}}{{range .Entries}}{{.Link}}{{end}}`,
+			})
+
+			indexator := NewIndexatorWithOptions(tempDir, Options{
+				TemplatePath: templatePath,
+				Transaction:  true,
+				KeepGoing:    tt.keepGoing,
+			})
+			if err := indexator.Start(); err == nil {
+				t.Fatal("Start() should fail when a directory cannot be indexed")
+			}
+
+			byPath := make(map[string]DirReport)
+			for _, dir := range indexator.Report().Dirs {
+				byPath[dir.Path] = dir
+			}
+			if b := byPath["B"]; b.Action != DirFailed {
+				t.Errorf("B = %+v, want failed", b)
+			}
+			a := byPath["A"]
+			if a.Action != tt.wantA {
+				t.Errorf("A = %+v, want %q", a, tt.wantA)
+			}
+			_, err := os.Stat(filepath.Join(tempDir, "A/A.md"))
+			if written := err == nil; written != (a.Action == DirCreated) {
+				t.Errorf("A/A.md written = %v, but A is reported %q", written, a.Action)
+			}
+			if a.Action == DirFailed && (a.Error == "" || a.BytesWritten != 0) {
+				t.Errorf("abandoned A = %+v, want an error and no bytes written", a)
+			}
+		})
+	}
+}
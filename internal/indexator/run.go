@@ -0,0 +1,97 @@
+package indexator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runIDFormat is the layout of the start time that run IDs begin with. Runs
+// started within the same second get a "-N" suffix.
+const runIDFormat = "20060102_150405"
+
+// beginRun starts a new run. Its ID, which names its directory in the
+// backup store and its journal, is only picked by the first operation that
+// needs it.
+func (idx *Indexator) beginRun() {
+	idx.runMu.Lock()
+	defer idx.runMu.Unlock()
+	idx.runID = ""
+}
+
+// beginIndexRun starts a run that indexes directories: on top of beginRun it
+// sets the generation time of its index files and starts its report and the
+// tracking of its index files
+func (idx *Indexator) beginIndexRun() {
+	idx.runTime = time.Now()
+	idx.beginRun()
+	idx.report = newRunReport(idx.runTime)
+	idx.indexes = newRunIndexes()
+	idx.failedDirs = nil
+	idx.handWrittenMu.Lock()
+	idx.handWritten = nil
+	idx.handWrittenMu.Unlock()
+}
+
+// endRun applies the retention settings once a run that may have written
+// files is done. Failures are only logged.
+func (idx *Indexator) endRun() {
+	idx.pruneBackups()
+	idx.pruneJournals()
+}
+
+// currentRunID returns the ID of the current run, picking one that is not
+// used in the backup store or the journal the first time
+func (idx *Indexator) currentRunID() string {
+	idx.runMu.Lock()
+	defer idx.runMu.Unlock()
+
+	if idx.runID == "" {
+		base := time.Now().Format(runIDFormat)
+		id := base
+		for n := 2; idx.runIDTaken(id); n++ {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		idx.runID = id
+	}
+	return idx.runID
+}
+
+// runIDTaken reports whether a run ID is already used by a previous run
+func (idx *Indexator) runIDTaken(id string) bool {
+	for _, path := range []string{filepath.Join(idx.backupRoot(), id), idx.journalPath(id)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// runIDTime returns the start time encoded in a run ID
+func runIDTime(id string) (time.Time, bool) {
+	if len(id) < len(runIDFormat) {
+		return time.Time{}, false
+	}
+	suffix := id[len(runIDFormat):]
+	if suffix != "" && !strings.HasPrefix(suffix, "-") {
+		return time.Time{}, false
+	}
+	runTime, err := time.ParseInLocation(runIDFormat, id[:len(runIDFormat)], time.Local)
+	return runTime, err == nil
+}
+
+// newerRun reports whether run a started after run b
+func newerRun(a, b string) bool {
+	timeA, _ := runIDTime(a)
+	timeB, _ := runIDTime(b)
+	if !timeA.Equal(timeB) {
+		return timeA.After(timeB)
+	}
+	// Same second: compare the numeric suffixes, where none comes first
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
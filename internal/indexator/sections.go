@@ -0,0 +1,109 @@
+package indexator
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Entry kinds used to group index entries into sections
+const (
+	KindSubfolders  = "subfolders"
+	KindNotes       = "notes"
+	KindCanvases    = "canvases"
+	KindAttachments = "attachments"
+)
+
+// IsFileKind reports whether file extensions can be mapped to kind, which is
+// any kind but KindSubfolders
+func IsFileKind(kind string) bool {
+	switch kind {
+	case KindNotes, KindCanvases, KindAttachments:
+		return true
+	}
+	return false
+}
+
+// Section is a headed group of entries of the same kind
+type Section struct {
+	// Kind is one of KindSubfolders, KindNotes, KindCanvases or KindAttachments
+	Kind string
+	// Title is the section heading
+	Title string
+	// Entries holds the links of this section in sorted order
+	Entries []Entry
+}
+
+// sectionOrder lists section kinds in the order they appear in an index
+var sectionOrder = []struct {
+	kind  string
+	title string
+}{
+	{KindSubfolders, "Subfolders"},
+	{KindNotes, "Notes"},
+	{KindCanvases, "Canvases"},
+	{KindAttachments, "Attachments"},
+}
+
+// defaultKinds maps file extensions to entry kinds. Files with any other
+// extension are attachments.
+var defaultKinds = map[string]string{
+	".md":     KindNotes,
+	".canvas": KindCanvases,
+}
+
+const groupedTemplateText = `{{range $i, $section := .Sections}}{{if $i}}
+{{end}}## {{$section.Title}}
+{{range $section.Entries}}{{.Link}}
+{{end}}{{end}}`
+
+var groupedTemplate = template.Must(template.New("grouped").Parse(groupedTemplateText))
+
+// normalizeKinds merges user-defined extension mappings over the defaults
+func normalizeKinds(kinds map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaultKinds)+len(kinds))
+	for ext, kind := range defaultKinds {
+		merged[ext] = kind
+	}
+	for ext, kind := range kinds {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		merged[ext] = kind
+	}
+	return merged
+}
+
+// entryKind classifies an entry by its extension
+func (idx *Indexator) entryKind(entry Entry) string {
+	if entry.IsDir {
+		return KindSubfolders
+	}
+
+	kinds := idx.kinds
+	if kinds == nil {
+		kinds = defaultKinds
+	}
+	if kind, ok := kinds[strings.ToLower(filepath.Ext(entry.Name))]; ok {
+		return kind
+	}
+	return KindAttachments
+}
+
+// buildSections groups entries by kind, omitting empty sections
+func buildSections(entries []Entry) []Section {
+	var sections []Section
+	for _, s := range sectionOrder {
+		section := Section{Kind: s.kind, Title: s.title}
+		for _, entry := range entries {
+			if entry.Kind == s.kind {
+				section.Entries = append(section.Entries, entry)
+			}
+		}
+		if len(section.Entries) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
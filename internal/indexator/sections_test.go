@@ -0,0 +1,72 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_Start_WithGroup(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := []string{
+		"project/brief.md",
+		"project/board.canvas",
+		"project/diagram.excalidraw",
+		"project/spec.pdf",
+		"project/photo.PNG",
+		"project/research/paper.md",
+	}
+	for _, file := range files {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{
+		Group: true,
+		Kinds: map[string]string{"excalidraw": KindCanvases},
+	})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "project/project.md"))
+	if err != nil {
+		t.Fatalf("Failed to read project index: %v", err)
+	}
+
+	expected := generatedMarker + `
+## Subfolders
+[[project/research/research.md]]
+
+## Notes
+[[project/brief.md]]
+
+## Canvases
+[[project/board.canvas]]
+[[project/diagram.excalidraw]]
+
+## Attachments
+[[project/photo.PNG]]
+[[project/spec.pdf]]
+`
+	if string(content) != expected {
+		t.Errorf("project index = %q, want %q", string(content), expected)
+	}
+
+	// Empty sections are omitted
+	content, err = os.ReadFile(filepath.Join(tempDir, "project/research/research.md"))
+	if err != nil {
+		t.Fatalf("Failed to read research index: %v", err)
+	}
+
+	expected = generatedMarker + "\n## Notes\n[[project/research/paper.md]]\n"
+	if string(content) != expected {
+		t.Errorf("research index = %q, want %q", string(content), expected)
+	}
+}
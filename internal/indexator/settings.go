@@ -0,0 +1,270 @@
+package indexator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nzb3/obsidian-index/internal/ignore"
+	"gopkg.in/yaml.v3"
+)
+
+// OverrideFileNames are the names of per-directory settings files, in
+// lookup order. Settings apply to the directory and its descendants.
+var OverrideFileNames = []string{".obsidian-index.yaml", ".obsidian-index.yml", ".obsidian-index.json"}
+
+// vaultConfigFileName is the tool's configuration file in the vault root,
+// which is never listed in the root index
+const vaultConfigFileName = "obsidian-index.yaml"
+
+// defaultNaming names index files after their directory
+const defaultNaming = "{dir}.md"
+
+// dirOverride is the content of a per-directory settings file. Unset fields
+// inherit the value of the parent directory.
+type dirOverride struct {
+	Sort         *string  `yaml:"sort" json:"sort"`
+	FoldersFirst *bool    `yaml:"folders_first" json:"folders_first"`
+	Group        *bool    `yaml:"group" json:"group"`
+	Titles       *bool    `yaml:"titles" json:"titles"`
+	Template     *string  `yaml:"template" json:"template"`
+	Exclude      []string `yaml:"exclude" json:"exclude"`
+	Depth        *int     `yaml:"depth" json:"depth"`
+	Naming       *string  `yaml:"naming" json:"naming"`
+	Skip         *bool    `yaml:"skip" json:"skip"`
+}
+
+// dirSettings are the effective settings of a single directory
+type dirSettings struct {
+	sortBy       string
+	foldersFirst bool
+	group        bool
+	titles       bool
+	tmpl         *template.Template
+	// naming is the index file name pattern; {dir} is replaced by the
+	// directory name
+	naming string
+	// maxDepth is the deepest vault-relative depth that is indexed, or -1
+	maxDepth int
+	// excludes are the global exclude patterns followed by the patterns
+	// declared in settings files of the directory and its ancestors
+	excludes []ignore.Pattern
+	skip     bool
+}
+
+// baseSettings returns the settings derived from the Indexator options
+func (idx *Indexator) baseSettings() dirSettings {
+	sortBy := idx.sortBy
+	if sortBy == "" {
+		sortBy = SortName
+	}
+
+	var excludes []ignore.Pattern
+	for _, exclude := range idx.excludeDirs {
+		pattern, err := ignore.Parse(exclude, ".")
+		if err != nil {
+			slog.Warn("ignoring invalid exclude pattern", "pattern", exclude, "error", err)
+			continue
+		}
+		excludes = append(excludes, pattern)
+	}
+
+	return dirSettings{
+		excludes:     excludes,
+		sortBy:       sortBy,
+		foldersFirst: idx.foldersFirst,
+		group:        idx.group,
+		titles:       idx.titles,
+		tmpl:         idx.tmpl,
+		naming:       defaultNaming,
+		maxDepth:     -1,
+	}
+}
+
+// settingsFor resolves the settings of a vault-relative directory by
+// merging settings files of the directory and its ancestors over the
+// global options
+func (idx *Indexator) settingsFor(relDir string) (*dirSettings, error) {
+	idx.settingsMu.Lock()
+	defer idx.settingsMu.Unlock()
+	return idx.settingsForLocked(relDir)
+}
+
+// settingsForLocked is settingsFor for callers holding settingsMu
+func (idx *Indexator) settingsForLocked(relDir string) (*dirSettings, error) {
+	key := cleanRelPath(relDir)
+	if s, ok := idx.settings[key]; ok {
+		return s, nil
+	}
+
+	var s dirSettings
+	if key == "." {
+		s = idx.baseSettings()
+	} else {
+		parent, err := idx.settingsForLocked(path.Dir(key))
+		if err != nil {
+			return nil, err
+		}
+		s = *parent
+	}
+
+	if strategy, ok := idx.sortOverrides[key]; ok {
+		s.sortBy = strategy
+	}
+
+	override, file, err := idx.loadOverride(key)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		if err := idx.applyOverride(&s, key, file, override); err != nil {
+			return nil, err
+		}
+	}
+
+	if idx.settings == nil {
+		idx.settings = make(map[string]*dirSettings)
+	}
+	idx.settings[key] = &s
+	return &s, nil
+}
+
+// settingsOrBase is settingsFor for callers that cannot fail. Invalid
+// settings files are reported while collecting directories, so falling back
+// to the global settings here only affects directories outside a run.
+func (idx *Indexator) settingsOrBase(relDir string) *dirSettings {
+	s, err := idx.settingsFor(relDir)
+	if err != nil {
+		slog.Warn("failed to resolve directory settings", "directory", relDir, "error", err)
+		base := idx.baseSettings()
+		return &base
+	}
+	return s
+}
+
+// loadOverride reads the settings file of a directory, if there is one
+func (idx *Indexator) loadOverride(relDir string) (*dirOverride, string, error) {
+	for _, name := range OverrideFileNames {
+		file := filepath.Join(idx.vaultPath, filepath.FromSlash(relDir), name)
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, file, fmt.Errorf("failed to read settings file %s: %w", file, err)
+		}
+
+		var override dirOverride
+		if strings.HasSuffix(name, ".json") {
+			err = json.Unmarshal(content, &override)
+		} else {
+			err = yaml.Unmarshal(content, &override)
+		}
+		if err != nil {
+			return nil, file, fmt.Errorf("failed to parse settings file %s: %w", file, err)
+		}
+		return &override, file, nil
+	}
+	return nil, "", nil
+}
+
+// applyOverride merges a settings file over inherited settings
+func (idx *Indexator) applyOverride(s *dirSettings, relDir, file string, o *dirOverride) error {
+	if o.Sort != nil {
+		if !IsSortStrategy(*o.Sort) {
+			return fmt.Errorf("invalid sort strategy %q in %s", *o.Sort, file)
+		}
+		s.sortBy = *o.Sort
+	}
+	if o.FoldersFirst != nil {
+		s.foldersFirst = *o.FoldersFirst
+	}
+	if o.Group != nil {
+		s.group = *o.Group
+	}
+	if o.Titles != nil {
+		s.titles = *o.Titles
+	}
+	if o.Template != nil {
+		templatePath := *o.Template
+		if !filepath.IsAbs(templatePath) {
+			templatePath = filepath.Join(filepath.Dir(file), templatePath)
+		}
+		tmpl, err := idx.parseTemplateFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("invalid template in %s: %w", file, err)
+		}
+		s.tmpl = tmpl
+	}
+	if len(o.Exclude) > 0 {
+		excludes := make([]ignore.Pattern, 0, len(s.excludes)+len(o.Exclude))
+		excludes = append(excludes, s.excludes...)
+		for _, exclude := range o.Exclude {
+			pattern, err := ignore.Parse(exclude, relDir)
+			if err != nil {
+				return fmt.Errorf("invalid exclude in %s: %w", file, err)
+			}
+			excludes = append(excludes, pattern)
+		}
+		s.excludes = excludes
+	}
+	if o.Depth != nil {
+		if *o.Depth < 0 {
+			return fmt.Errorf("depth cannot be negative in %s", file)
+		}
+		s.maxDepth = pathDepth(relDir) + *o.Depth
+	}
+	if o.Naming != nil {
+		naming := strings.TrimSpace(*o.Naming)
+		if naming == "" || strings.ContainsAny(naming, `/\`) {
+			return fmt.Errorf("invalid naming %q in %s", *o.Naming, file)
+		}
+		s.naming = naming
+	}
+	if o.Skip != nil {
+		s.skip = *o.Skip
+	}
+	return nil
+}
+
+// indexFileName returns the index file name for a directory with the given
+// base name
+func (s *dirSettings) indexFileName(dirName string) string {
+	naming := s.naming
+	if naming == "" {
+		naming = defaultNaming
+	}
+	return strings.ReplaceAll(naming, "{dir}", dirName)
+}
+
+// isOverrideFile reports whether name is a per-directory settings file
+func isOverrideFile(name string) bool {
+	for _, overrideName := range OverrideFileNames {
+		if name == overrideName {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanRelPath normalizes a vault-relative path to slash form
+func cleanRelPath(relPath string) string {
+	clean := path.Clean(strings.Trim(filepath.ToSlash(relPath), "/"))
+	if clean == "" {
+		return "."
+	}
+	return clean
+}
+
+// pathDepth returns the number of components of a vault-relative path
+func pathDepth(relPath string) int {
+	if relPath == "." {
+		return 0
+	}
+	return strings.Count(relPath, "/") + 1
+}
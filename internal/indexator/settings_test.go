@@ -0,0 +1,110 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for file, content := range files {
+		fullPath := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+}
+
+func TestIndexator_Start_WithOverrideFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writeTestFiles(t, tempDir, map[string]string{
+		"Projects/.obsidian-index.yaml":     "group: true\nnaming: _index.md\n",
+		"Projects/alpha/plan.md":            "# Plan",
+		"Projects/alpha/board.canvas":       "{}",
+		"Projects/brief.md":                 "# Brief",
+		"Archive/.obsidian-index.json":      `{"skip": true}`,
+		"Archive/old.md":                    "# Old",
+		"Notes/.obsidian-index.yml":         "sort: natural\ndepth: 1\nexclude: [drafts]\n",
+		"Notes/10-later.md":                 "# Later",
+		"Notes/2-sooner.md":                 "# Sooner",
+		"Notes/drafts/wip.md":               "# WIP",
+		"Notes/topics/topic.md":             "# Topic",
+		"Notes/topics/deep/too-deep.md":     "# Too deep",
+		"Notes/topics/.obsidian-index.yaml": "folders_first: true\n",
+	})
+
+	indexator := NewIndexator(tempDir)
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	// Naming and grouping apply to Projects and are inherited by alpha
+	content, err := os.ReadFile(filepath.Join(tempDir, "Projects/alpha/_index.md"))
+	if err != nil {
+		t.Fatalf("Failed to read alpha index: %v", err)
+	}
+	expected := generatedMarker + "\n## Notes\n[[Projects/alpha/plan.md]]\n\n## Canvases\n[[Projects/alpha/board.canvas]]\n"
+	if string(content) != expected {
+		t.Errorf("alpha index = %q, want %q", string(content), expected)
+	}
+
+	content, err = os.ReadFile(filepath.Join(tempDir, "Projects/_index.md"))
+	if err != nil {
+		t.Fatalf("Failed to read Projects index: %v", err)
+	}
+	if strings.Contains(string(content), ".obsidian-index") {
+		t.Error("Settings files should not be listed in indexes")
+	}
+	if !strings.Contains(string(content), "[[Projects/alpha/_index.md]]") {
+		t.Error("Projects index should link to the renamed alpha index")
+	}
+
+	// Archive is skipped entirely
+	if _, err := os.Stat(filepath.Join(tempDir, "Archive/Archive.md")); err == nil {
+		t.Error("Skipped directory should not have an index file")
+	}
+
+	// Notes is sorted naturally and drafts is excluded
+	content, err = os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+	if err != nil {
+		t.Fatalf("Failed to read Notes index: %v", err)
+	}
+	expected = generatedMarker + "\n[[Notes/2-sooner.md]]\n[[Notes/10-later.md]]\n[[Notes/topics/topics.md]]\n"
+	if string(content) != expected {
+		t.Errorf("Notes index = %q, want %q", string(content), expected)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Notes/drafts/drafts.md")); err == nil {
+		t.Error("Excluded directory should not have an index file")
+	}
+
+	// Depth limits indexing to one level below Notes
+	if _, err := os.Stat(filepath.Join(tempDir, "Notes/topics/topics.md")); err != nil {
+		t.Error("Directory within depth should have an index file")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Notes/topics/deep/deep.md")); err == nil {
+		t.Error("Directory beyond depth should not have an index file")
+	}
+}
+
+func TestIndexator_Start_WithInvalidOverrideFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writeTestFiles(t, tempDir, map[string]string{
+		"Notes/.obsidian-index.yaml": "sort: random\n",
+		"Notes/note.md":              "# Note",
+	})
+
+	err := NewIndexator(tempDir).Start()
+	if err == nil {
+		t.Fatal("Start() should fail with an invalid settings file")
+	}
+	if !strings.Contains(err.Error(), "random") {
+		t.Errorf("error should mention the invalid value, got: %v", err)
+	}
+}
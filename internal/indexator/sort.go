@@ -0,0 +1,234 @@
+package indexator
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort strategies for index entries
+const (
+	// SortName keeps plain byte order, which is what os.ReadDir returns
+	SortName = "name"
+	// SortNatural compares embedded numbers numerically and ignores case
+	SortNatural = "natural"
+	// SortCaseInsensitive compares names ignoring case
+	SortCaseInsensitive = "nocase"
+	// SortModTime puts the most recently modified entries first
+	SortModTime = "mtime"
+	// SortCreateTime puts the most recently created entries first
+	SortCreateTime = "ctime"
+	// SortOrder sorts by the numeric order or weight frontmatter property;
+	// entries without one come last
+	SortOrder = "order"
+)
+
+// SortStrategies lists every supported sort strategy
+var SortStrategies = []string{SortName, SortNatural, SortCaseInsensitive, SortModTime, SortCreateTime, SortOrder}
+
+// IsSortStrategy reports whether strategy is one of SortStrategies
+func IsSortStrategy(strategy string) bool {
+	for _, s := range SortStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// orderKeys are the frontmatter properties consulted by SortOrder
+var orderKeys = []string{"order", "weight"}
+
+type sortItem struct {
+	entry     Entry
+	modTime   time.Time
+	birthTime time.Time
+	order     float64
+	hasOrder  bool
+}
+
+// normalizeSortOverrides converts override keys to clean slash-separated
+// vault-relative paths
+func normalizeSortOverrides(overrides map[string]string) map[string]string {
+	normalized := make(map[string]string, len(overrides))
+	for dir, strategy := range overrides {
+		normalized[cleanRelPath(dir)] = strategy
+	}
+	return normalized
+}
+
+// sortEntries orders entries of dirPath according to the configured strategy
+func (idx *Indexator) sortEntries(dirPath string, entries []Entry) {
+	settings := idx.settingsOrBase(dirPath)
+	strategy := settings.sortBy
+
+	items := make([]sortItem, len(entries))
+	for i, entry := range entries {
+		items[i] = idx.newSortItem(entry, strategy)
+	}
+
+	less := sortLess(strategy)
+	sort.SliceStable(items, func(i, j int) bool {
+		return less(&items[i], &items[j])
+	})
+
+	if settings.foldersFirst {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].entry.IsDir && !items[j].entry.IsDir
+		})
+	}
+
+	for i := range items {
+		entries[i] = items[i].entry
+	}
+}
+
+func (idx *Indexator) newSortItem(entry Entry, strategy string) sortItem {
+	item := sortItem{entry: entry}
+
+	switch strategy {
+	case SortModTime, SortCreateTime:
+		// Folders are sorted by the directory itself, not by their index
+		// file, which is rewritten on every run
+		statPath := entry.absPath
+		if entry.IsDir {
+			statPath = filepath.Dir(entry.absPath)
+		}
+		info, err := os.Stat(statPath)
+		if err != nil {
+			slog.Warn("failed to stat entry for sorting", "path", statPath, "error", err)
+			return item
+		}
+		item.modTime = info.ModTime()
+		item.birthTime = birthTime(statPath, info)
+	case SortOrder:
+		if !strings.EqualFold(filepath.Ext(entry.absPath), ".md") {
+			return item
+		}
+		fields, err := readFrontmatter(entry.absPath)
+		if err != nil {
+			slog.Warn("failed to read frontmatter for sorting", "path", entry.absPath, "error", err)
+			return item
+		}
+		item.order, item.hasOrder = orderValue(fields)
+	}
+
+	return item
+}
+
+func sortLess(strategy string) func(a, b *sortItem) bool {
+	switch strategy {
+	case SortNatural:
+		return func(a, b *sortItem) bool {
+			return naturalLess(a.entry.Name, b.entry.Name)
+		}
+	case SortCaseInsensitive:
+		return func(a, b *sortItem) bool {
+			la, lb := strings.ToLower(a.entry.Name), strings.ToLower(b.entry.Name)
+			if la != lb {
+				return la < lb
+			}
+			return a.entry.Name < b.entry.Name
+		}
+	case SortModTime:
+		return func(a, b *sortItem) bool {
+			if !a.modTime.Equal(b.modTime) {
+				return a.modTime.After(b.modTime)
+			}
+			return naturalLess(a.entry.Name, b.entry.Name)
+		}
+	case SortCreateTime:
+		return func(a, b *sortItem) bool {
+			if !a.birthTime.Equal(b.birthTime) {
+				return a.birthTime.After(b.birthTime)
+			}
+			return naturalLess(a.entry.Name, b.entry.Name)
+		}
+	case SortOrder:
+		return func(a, b *sortItem) bool {
+			if a.hasOrder != b.hasOrder {
+				return a.hasOrder
+			}
+			if a.hasOrder && a.order != b.order {
+				return a.order < b.order
+			}
+			return naturalLess(a.entry.Name, b.entry.Name)
+		}
+	default:
+		return func(a, b *sortItem) bool {
+			return a.entry.Name < b.entry.Name
+		}
+	}
+}
+
+// orderValue extracts the numeric order or weight property from frontmatter
+func orderValue(fields map[string]any) (float64, bool) {
+	for _, key := range orderKeys {
+		switch v := fields[key].(type) {
+		case int:
+			return float64(v), true
+		case float64:
+			return v, true
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// naturalLess compares strings so that embedded numbers are ordered by value
+// ("2-bar" before "10-foo") and letters are compared case-insensitively.
+// Names that compare equal fall back to byte order.
+func naturalLess(a, b string) bool {
+	ra, rb := a, b
+	for ra != "" && rb != "" {
+		ca, restA := nextChunk(ra)
+		cb, restB := nextChunk(rb)
+
+		if cmp := compareChunks(ca, cb); cmp != 0 {
+			return cmp < 0
+		}
+		ra, rb = restA, restB
+	}
+
+	if ra != rb {
+		return ra == ""
+	}
+	return a < b
+}
+
+// nextChunk splits off the leading run of digits or non-digits
+func nextChunk(s string) (chunk, rest string) {
+	digit := isDigit(s[0])
+
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareChunks(a, b string) int {
+	if isDigits(a) && isDigits(b) {
+		ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(ta) != len(tb) {
+			return len(ta) - len(tb)
+		}
+		return strings.Compare(ta, tb)
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func isDigits(s string) bool {
+	return s != "" && isDigit(s[0])
+}
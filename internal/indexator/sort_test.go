@@ -0,0 +1,144 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"10-foo", "2-bar", "Beta", "alpha", "file1", "file01", "file10", "file2"}
+	sort.SliceStable(names, func(i, j int) bool {
+		return naturalLess(names[i], names[j])
+	})
+
+	expected := []string{"2-bar", "10-foo", "alpha", "Beta", "file01", "file1", "file2", "file10"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("naturalLess order = %v, want %v", names, expected)
+	}
+}
+
+func TestIndexator_sortEntries(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"10-foo.md": "---\norder: 2\n---\n# Foo",
+		"2-bar.md":  "---\nweight: 1\n---\n# Bar",
+		"Zeta.md":   "# Zeta",
+		"alpha.md":  "# Alpha",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", name, err)
+		}
+	}
+
+	base := time.Now().Add(-time.Hour)
+	mtimes := map[string]time.Duration{"10-foo.md": 1, "2-bar.md": 3, "Zeta.md": 2, "alpha.md": 0}
+	for name, offset := range mtimes {
+		modTime := base.Add(offset * time.Minute)
+		if err := os.Chtimes(filepath.Join(tempDir, name), modTime, modTime); err != nil {
+			t.Fatalf("Failed to set times of %s: %v", name, err)
+		}
+	}
+
+	newEntries := func() []Entry {
+		var entries []Entry
+		for _, name := range []string{"10-foo.md", "2-bar.md", "Zeta.md", "alpha.md"} {
+			entries = append(entries, Entry{Name: name, absPath: filepath.Join(tempDir, name)})
+		}
+		return append(entries, Entry{Name: "sub", IsDir: true, absPath: filepath.Join(tempDir, "sub", "sub.md")})
+	}
+
+	tests := []struct {
+		name         string
+		sortBy       string
+		foldersFirst bool
+		expected     []string
+	}{
+		{
+			name:     "default is byte order",
+			expected: []string{"10-foo.md", "2-bar.md", "Zeta.md", "alpha.md", "sub"},
+		},
+		{
+			name:     "natural",
+			sortBy:   SortNatural,
+			expected: []string{"2-bar.md", "10-foo.md", "alpha.md", "sub", "Zeta.md"},
+		},
+		{
+			name:     "case insensitive",
+			sortBy:   SortCaseInsensitive,
+			expected: []string{"10-foo.md", "2-bar.md", "alpha.md", "sub", "Zeta.md"},
+		},
+		{
+			name:         "natural with folders first",
+			sortBy:       SortNatural,
+			foldersFirst: true,
+			expected:     []string{"sub", "2-bar.md", "10-foo.md", "alpha.md", "Zeta.md"},
+		},
+		{
+			name:     "order property",
+			sortBy:   SortOrder,
+			expected: []string{"2-bar.md", "10-foo.md", "alpha.md", "sub", "Zeta.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := &Indexator{vaultPath: tempDir, sortBy: tt.sortBy, foldersFirst: tt.foldersFirst}
+			entries := newEntries()
+			idx.sortEntries(".", entries)
+
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("sortEntries() = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+
+	t.Run("modification time", func(t *testing.T) {
+		idx := &Indexator{vaultPath: tempDir, sortBy: SortModTime}
+		entries := newEntries()[:4]
+		idx.sortEntries(".", entries)
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		expected := []string{"2-bar.md", "Zeta.md", "10-foo.md", "alpha.md"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("sortEntries() = %v, want %v", names, expected)
+		}
+	})
+}
+
+func TestIndexator_sortOverrides(t *testing.T) {
+	idx := NewIndexatorWithOptions("/vault", Options{
+		SortBy: SortNatural,
+		SortOverrides: map[string]string{
+			"Journal/":       SortModTime,
+			"Journal/Drafts": SortName,
+		},
+	})
+
+	tests := map[string]string{
+		".":                   SortNatural,
+		"Projects":            SortNatural,
+		"Journal":             SortModTime,
+		"Journal/2024":        SortModTime,
+		"Journal/Drafts":      SortName,
+		"Journal/Drafts/Old":  SortName,
+		"JournalArchive/2024": SortNatural,
+	}
+	for dir, expected := range tests {
+		if got := idx.settingsOrBase(dir).sortBy; got != expected {
+			t.Errorf("sort strategy of %q = %q, want %q", dir, got, expected)
+		}
+	}
+}
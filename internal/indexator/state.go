@@ -0,0 +1,275 @@
+package indexator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// StateDirName is the directory in the vault root where state is kept
+// between runs. Being hidden, it is never indexed.
+const StateDirName = ".obsidian-index"
+
+// stateFileName is the name of the manifest inside StateDirName
+const stateFileName = "state.json"
+
+// manifestVersion is bumped whenever the manifest format or the way index
+// files are rendered changes, which forces a full rebuild
+const manifestVersion = 1
+
+// manifest records what the previous run saw and generated, so that
+// directories that did not change can be skipped
+type manifest struct {
+	Version int `json:"version"`
+	// Settings is a fingerprint of the options, templates and Obsidian
+	// settings of the run; when it changes every directory is rebuilt
+	Settings string              `json:"settings"`
+	Dirs     map[string]dirState `json:"dirs"`
+}
+
+// dirState is the recorded state of a single directory
+type dirState struct {
+	// Entries hashes the directory listing along with the settings files
+	// that apply to the directory
+	Entries string `json:"entries"`
+	// Index hashes the index file of the directory, or is empty if there
+	// is none
+	Index string `json:"index"`
+}
+
+// manifestPath returns the location of the manifest in the vault
+func (idx *Indexator) manifestPath() string {
+	return filepath.Join(idx.vaultPath, StateDirName, stateFileName)
+}
+
+// loadManifest reads the manifest of the previous run. A missing manifest
+// yields nil; an unreadable one is logged and treated as missing.
+func (idx *Indexator) loadManifest() *manifest {
+	manifestPath := idx.manifestPath()
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read state manifest", "file", manifestPath, "error", err)
+		}
+		return nil
+	}
+
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		slog.Warn("ignoring invalid state manifest", "file", manifestPath, "error", err)
+		return nil
+	}
+	return &m
+}
+
+// saveManifest persists the manifest for the next run
+func (idx *Indexator) saveManifest(m *manifest) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(idx.vaultPath, StateDirName), 0755); err != nil {
+		slog.Error("failed to create state directory", "error", err)
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return idx.writeFileAtomic(idx.manifestPath(), content)
+}
+
+// planIncremental compares the vault with the manifest of the previous run.
+// It returns the manifest to record after the run and the set of
+// directories to index: those that changed and their ancestors. A nil set
+// means every directory.
+func (idx *Indexator) planIncremental(directories []string) (*manifest, map[string]bool, error) {
+	fingerprint, err := idx.settingsFingerprint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := &manifest{
+		Version:  manifestVersion,
+		Settings: fingerprint,
+		Dirs:     make(map[string]dirState, len(directories)),
+	}
+	for _, dir := range directories {
+		state, err := idx.dirState(dir)
+		if err != nil && idx.keepGoing {
+			// Not recorded, so the directory counts as changed
+			idx.skipFailedDir(dir, err)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		current.Dirs[dir] = state
+	}
+
+	if idx.full {
+		slog.Debug("full rebuild requested")
+		return current, nil, nil
+	}
+	previous := idx.loadManifest()
+	if previous == nil || previous.Version != manifestVersion || previous.Settings != fingerprint {
+		slog.Debug("no usable state manifest, rebuilding every directory")
+		return current, nil, nil
+	}
+
+	changed := make(map[string]bool)
+	for _, dir := range directories {
+		if previous.Dirs[dir] == current.Dirs[dir] {
+			continue
+		}
+		for d := dir; !changed[d]; d = path.Dir(d) {
+			changed[d] = true
+			if d == "." {
+				break
+			}
+		}
+	}
+
+	slog.Info("Indexing changed directories", "changed", len(changed), "total", len(directories))
+	return current, changed, nil
+}
+
+// settingsFingerprint hashes everything besides the directory contents that
+// affects the generated index files
+func (idx *Indexator) settingsFingerprint() (string, error) {
+	fingerprint := struct {
+		MissingMarkers   string            `json:"missing_markers"`
+		Excludes         []string          `json:"excludes"`
+		SortBy           string            `json:"sort"`
+		SortOverrides    map[string]string `json:"sort_dirs"`
+		FoldersFirst     bool              `json:"folders_first"`
+		Group            bool              `json:"group"`
+		Kinds            map[string]string `json:"kinds"`
+		Titles           bool              `json:"titles"`
+		RespectGitignore bool              `json:"respect_gitignore"`
+		Templates        map[string]string `json:"templates"`
+		ObsidianSettings string            `json:"obsidian_settings"`
+	}{
+		MissingMarkers:   idx.missingMarkers,
+		Excludes:         idx.excludeDirs,
+		SortBy:           idx.sortBy,
+		SortOverrides:    idx.sortOverrides,
+		FoldersFirst:     idx.foldersFirst,
+		Group:            idx.group,
+		Kinds:            idx.kinds,
+		Titles:           idx.titles,
+		RespectGitignore: idx.respectGitignore,
+		Templates:        make(map[string]string),
+	}
+
+	// Templates referenced by settings files have been parsed while
+	// collecting directories
+	idx.templatesMu.Lock()
+	for templatePath := range idx.templates {
+		fingerprint.Templates[templatePath] = hashFile(templatePath)
+	}
+	idx.templatesMu.Unlock()
+	if !idx.ignoreObsidianExcludes {
+		fingerprint.ObsidianSettings = hashFile(filepath.Join(idx.vaultPath, ".obsidian", "app.json"))
+	}
+
+	content, err := json.Marshal(fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode settings fingerprint: %w", err)
+	}
+	return hashBytes(content), nil
+}
+
+// dirState computes the current state of a vault-relative directory
+func (idx *Indexator) dirState(dir string) (dirState, error) {
+	fullPath := filepath.Join(idx.vaultPath, dir)
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return dirState{}, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	indexName := idx.indexFileName(fullPath)
+	h := sha256.New()
+	fmt.Fprintf(h, "rules %s\n", idx.rulesHash(dir))
+	for _, entry := range entries {
+		if entry.Name() == indexName || entry.Name() == indexName+".tmp" {
+			continue
+		}
+		if dir == "." && entry.Name() == StateDirName {
+			continue
+		}
+
+		entryPath := filepath.Join(fullPath, entry.Name())
+		if entry.IsDir() {
+			_, err := os.Stat(filepath.Join(entryPath, idx.indexFileName(entryPath)))
+			fmt.Fprintf(h, "d %q %t\n", entry.Name(), err == nil)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// The entry vanished since the directory was read
+			continue
+		}
+		fmt.Fprintf(h, "f %q %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return dirState{
+		Entries: hex.EncodeToString(h.Sum(nil)),
+		Index:   hashFile(filepath.Join(fullPath, indexName)),
+	}, nil
+}
+
+// rulesHash hashes the settings and .gitignore files of a vault-relative
+// directory and its ancestors, which decide how the directory is indexed
+func (idx *Indexator) rulesHash(dir string) string {
+	idx.rulesMu.Lock()
+	defer idx.rulesMu.Unlock()
+	return idx.rulesHashLocked(dir)
+}
+
+// rulesHashLocked is rulesHash for callers holding rulesMu
+func (idx *Indexator) rulesHashLocked(dir string) string {
+	dir = cleanRelPath(dir)
+	if hash, ok := idx.rules[dir]; ok {
+		return hash
+	}
+
+	h := sha256.New()
+	if dir != "." {
+		fmt.Fprintf(h, "parent %s\n", idx.rulesHashLocked(path.Dir(dir)))
+	}
+	names := append([]string{}, OverrideFileNames...)
+	if idx.respectGitignore {
+		names = append(names, gitignoreFileName)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s %s\n", name, hashFile(filepath.Join(idx.vaultPath, filepath.FromSlash(dir), name)))
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	if idx.rules == nil {
+		idx.rules = make(map[string]string)
+	}
+	idx.rules[dir] = hash
+	return hash
+}
+
+// hashFile returns the hash of a file's content, or an empty string if the
+// file cannot be read
+func hashFile(filePath string) string {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	return hashBytes(content)
+}
+
+func hashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
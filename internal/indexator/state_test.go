@@ -0,0 +1,212 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Start_Incremental(t *testing.T) {
+	newVault := func(t *testing.T) string {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/one.md": "# One",
+			"B/two.md": "# Two",
+		})
+		return tempDir
+	}
+	opts := Options{Update: true, Titles: true, Incremental: true}
+
+	// retitle rewrites a note with content of the same size and restores
+	// its modification time, so only a full rebuild picks the change up
+	retitle := func(t *testing.T, notePath, content string) {
+		t.Helper()
+		info, err := os.Stat(notePath)
+		if err != nil {
+			t.Fatalf("Failed to stat note: %v", err)
+		}
+		if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+		if err := os.Chtimes(notePath, info.ModTime(), info.ModTime()); err != nil {
+			t.Fatalf("Failed to restore modification time: %v", err)
+		}
+	}
+
+	readIndex := func(t *testing.T, indexPath string) string {
+		t.Helper()
+		content, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("only changed directories are regenerated", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, StateDirName, stateFileName)); err != nil {
+			t.Fatalf("State manifest should be written: %v", err)
+		}
+
+		retitle(t, filepath.Join(tempDir, "B/two.md"), "# Owt")
+		writeTestFiles(t, tempDir, map[string]string{"A/three.md": "# Three"})
+
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("Second Start() failed: %v", err)
+		}
+
+		if content := readIndex(t, filepath.Join(tempDir, "A/A.md")); !strings.Contains(content, "[[A/three|Three]]") {
+			t.Errorf("Changed directory should be regenerated, got %q", content)
+		}
+		if content := readIndex(t, filepath.Join(tempDir, "B/B.md")); !strings.Contains(content, "[[B/two|Two]]") {
+			t.Errorf("Unchanged directory should be skipped, got %q", content)
+		}
+	})
+
+	t.Run("unchanged vault has nothing to index", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		indexator := NewIndexatorWithOptions(tempDir, opts)
+		directories, err := indexator.CollectDirectories()
+		if err != nil {
+			t.Fatalf("CollectDirectories() failed: %v", err)
+		}
+		_, changed, err := indexator.planIncremental(directories)
+		if err != nil {
+			t.Fatalf("planIncremental() failed: %v", err)
+		}
+		if changed == nil || len(changed) != 0 {
+			t.Errorf("planIncremental() changed = %v, want none", changed)
+		}
+	})
+
+	t.Run("full rebuild regenerates everything", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+
+		retitle(t, filepath.Join(tempDir, "B/two.md"), "# Owt")
+
+		fullOpts := opts
+		fullOpts.Full = true
+		if err := NewIndexatorWithOptions(tempDir, fullOpts).Start(); err != nil {
+			t.Fatalf("Full Start() failed: %v", err)
+		}
+
+		if content := readIndex(t, filepath.Join(tempDir, "B/B.md")); !strings.Contains(content, "[[B/two|Owt]]") {
+			t.Errorf("Full rebuild should regenerate every directory, got %q", content)
+		}
+	})
+
+	t.Run("deleted index is recreated", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+
+		if err := os.Remove(filepath.Join(tempDir, "B/B.md")); err != nil {
+			t.Fatalf("Failed to remove index: %v", err)
+		}
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("Second Start() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tempDir, "B/B.md")); err != nil {
+			t.Error("Deleted index file should be recreated")
+		}
+	})
+
+	t.Run("changed settings rebuild everything", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+
+		retitle(t, filepath.Join(tempDir, "B/two.md"), "# Owt")
+		writeTestFiles(t, tempDir, map[string]string{"B/.obsidian-index.yaml": "sort: natural\n"})
+
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("Second Start() failed: %v", err)
+		}
+
+		if content := readIndex(t, filepath.Join(tempDir, "B/B.md")); !strings.Contains(content, "[[B/two|Owt]]") {
+			t.Errorf("New settings file should mark the directory as changed, got %q", content)
+		}
+	})
+
+	t.Run("runs without update keep the manifest", func(t *testing.T) {
+		tempDir := newVault(t)
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+
+		// A gets a note, but its existing index is left as it is
+		writeTestFiles(t, tempDir, map[string]string{"A/three.md": "# Three"})
+		initOpts := opts
+		initOpts.Update = false
+		if err := NewIndexatorWithOptions(tempDir, initOpts).Start(); err != nil {
+			t.Fatalf("Start() without update failed: %v", err)
+		}
+
+		indexator := NewIndexatorWithOptions(tempDir, opts)
+		directories, err := indexator.CollectDirectories()
+		if err != nil {
+			t.Fatalf("CollectDirectories() failed: %v", err)
+		}
+		_, changed, err := indexator.planIncremental(directories)
+		if err != nil {
+			t.Fatalf("planIncremental() failed: %v", err)
+		}
+		if len(changed) != 2 || !changed["A"] || !changed["."] {
+			t.Errorf("planIncremental() changed = %v, want only A and its parent", changed)
+		}
+
+		if err := indexator.Start(); err != nil {
+			t.Fatalf("Start() with update failed: %v", err)
+		}
+		if content := readIndex(t, filepath.Join(tempDir, "A/A.md")); !strings.Contains(content, "[[A/three|Three]]") {
+			t.Errorf("Index left out of date should be regenerated, got %q", content)
+		}
+	})
+
+	t.Run("hand-written index files are not revisited", func(t *testing.T) {
+		tempDir := newVault(t)
+		writeTestFiles(t, tempDir, map[string]string{"B/B.md": "My own folder note"})
+		first := NewIndexatorWithOptions(tempDir, opts)
+		if err := first.Start(); err != nil {
+			t.Fatalf("First Start() failed: %v", err)
+		}
+		if files := first.HandWrittenFiles(); len(files) != 1 || files[0] != "B/B.md" {
+			t.Fatalf("HandWrittenFiles() = %v, want [B/B.md]", files)
+		}
+
+		second := NewIndexatorWithOptions(tempDir, opts)
+		if err := second.Start(); err != nil {
+			t.Fatalf("Second Start() failed: %v", err)
+		}
+		if files := second.HandWrittenFiles(); len(files) != 0 {
+			t.Errorf("unchanged directory with a hand-written index was indexed again: %v", files)
+		}
+	})
+
+	t.Run("dry run does not write the manifest", func(t *testing.T) {
+		tempDir := newVault(t)
+		dryOpts := opts
+		dryOpts.DryRun = true
+		if err := NewIndexatorWithOptions(tempDir, dryOpts).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tempDir, StateDirName)); err == nil {
+			t.Error("Dry run should not create the state directory")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package indexator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// Entry is a single link rendered into an index file
+type Entry struct {
+	// Name is the file or folder name
+	Name string
+	// Title is the display title of a note when titles are enabled, or the
+	// folder name for subfolders
+	Title string
+	// Path is the vault-relative path of the link target
+	Path string
+	// Link is the ready-to-use wikilink, e.g. [[notes/file.md]] or
+	// [[notes/202401011230|Title]] with titles enabled
+	Link string
+	// IsDir is true for links to subfolder indexes
+	IsDir bool
+	// Kind classifies the entry, see KindSubfolders and friends
+	Kind string
+
+	// absPath is the absolute path of the link target
+	absPath string
+}
+
+// IndexData is the data model passed to index templates
+type IndexData struct {
+	// Name is the directory name ("index" for the vault root)
+	Name string
+	// Path is the vault-relative directory path ("." for the vault root)
+	Path string
+	// ParentIndex is the vault-relative path of the parent index file,
+	// empty for the vault root
+	ParentIndex string
+	// Entries holds every link in listing order
+	Entries []Entry
+	// Folders holds the links to subfolder indexes
+	Folders []Entry
+	// Files holds the links to files
+	Files []Entry
+	// Sections groups the links by kind; empty sections are omitted
+	Sections []Section
+	// Generated is the time the current run started
+	Generated time.Time
+}
+
+const defaultTemplateText = `{{range .Entries}}{{.Link}}
+{{end}}`
+
+var defaultTemplate = template.Must(template.New("default").Parse(defaultTemplateText))
+
+// loadTemplate parses the user-defined template, if any
+func (idx *Indexator) loadTemplate() error {
+	if idx.templatePath == "" || idx.tmpl != nil {
+		return nil
+	}
+
+	tmpl, err := idx.parseTemplateFile(idx.templatePath)
+	if err != nil {
+		return err
+	}
+
+	idx.tmpl = tmpl
+	return nil
+}
+
+// resetTemplates drops the parsed templates
+func (idx *Indexator) resetTemplates() {
+	idx.templatesMu.Lock()
+	defer idx.templatesMu.Unlock()
+	idx.tmpl = nil
+	idx.templates = nil
+}
+
+// templateFiles returns the template files used by the last run
+func (idx *Indexator) templateFiles() []string {
+	idx.templatesMu.Lock()
+	defer idx.templatesMu.Unlock()
+	files := make([]string, 0, len(idx.templates))
+	for templatePath := range idx.templates {
+		files = append(files, templatePath)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// parseTemplateFile parses a template file, caching the result by path
+func (idx *Indexator) parseTemplateFile(templatePath string) (*template.Template, error) {
+	idx.templatesMu.Lock()
+	defer idx.templatesMu.Unlock()
+
+	if tmpl, ok := idx.templates[templatePath]; ok {
+		return tmpl, nil
+	}
+
+	text, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", templatePath, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(templatePath)).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", templatePath, err)
+	}
+
+	if idx.templates == nil {
+		idx.templates = make(map[string]*template.Template)
+	}
+	idx.templates[templatePath] = tmpl
+	return tmpl, nil
+}
+
+// renderIndex renders the body of an index file
+func renderIndex(data *IndexData, settings *dirSettings) (string, error) {
+	tmpl := settings.tmpl
+	if tmpl == nil && settings.group {
+		tmpl = groupedTemplate
+	}
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", tmpl.Name(), err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,80 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexator_Start_WithTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	structure := []string{
+		"projects/alpha/plan.md",
+		"projects/readme.md",
+	}
+	for _, file := range structure {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("# Content"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+
+	templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+	templateText := `---
+type: index
+---
+# {{.Name}} ({{.Path}})
+{{if .ParentIndex}}Up: [[{{.ParentIndex}}]]
+{{end}}{{range .Folders}}- 📁 {{.Link}}
+{{end}}{{range .Files}}- {{.Link}}
+{{end}}`
+	if err := os.WriteFile(templatePath, []byte(templateText), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{TemplatePath: templatePath})
+	if err := indexator.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "projects/projects.md"))
+	if err != nil {
+		t.Fatalf("Failed to read projects index: %v", err)
+	}
+
+	expected := `---
+type: index
+---
+` + generatedMarker + `
+# projects (projects)
+Up: [[index.md]]
+- 📁 [[projects/alpha/alpha.md]]
+- [[projects/readme.md]]
+`
+	if string(content) != expected {
+		t.Errorf("projects index = %q, want %q", string(content), expected)
+	}
+}
+
+func TestIndexator_Start_WithInvalidTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	templatePath := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{range .Entries}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{TemplatePath: templatePath})
+	err := indexator.Start()
+	if err == nil {
+		t.Fatal("Start() should fail with an invalid template")
+	}
+	if !strings.Contains(err.Error(), "broken.tmpl") {
+		t.Errorf("error should mention the template file, got: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package indexator
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// stagingDirName is the directory inside StateDirName where transactional
+// runs stage new index contents
+const stagingDirName = "staging"
+
+// transactional reports whether the changes of a run are staged and
+// committed together
+func (idx *Indexator) transactional() bool {
+	return idx.transaction && !idx.dryRun
+}
+
+// stagingDir returns the directory the current run stages new contents in
+func (idx *Indexator) stagingDir() string {
+	return filepath.Join(idx.vaultPath, StateDirName, stagingDirName, idx.currentRunID())
+}
+
+// runDirectories indexes the given vault-relative directories as
+// indexDirectories does. In a transactional run the changes are collected
+// instead and only committed once every directory was indexed, so a failing
+// directory leaves every index file untouched; in keep-going mode the
+// changes of the other directories are still committed. When state is not
+// nil the new state of each directory is recorded in it.
+func (idx *Indexator) runDirectories(directories []string, state *manifest) error {
+	if !idx.transactional() || idx.plan != nil {
+		indexErr := idx.indexDirectories(directories, state)
+		if indexErr != nil && !idx.keptGoing(indexErr) {
+			return indexErr
+		}
+		removals, err := idx.removeObsolete()
+		if err != nil {
+			return err
+		}
+		if state != nil && idx.plan == nil {
+			// Removals change the state of their directories
+			for _, change := range removals {
+				dir := path.Dir(change.Path)
+				if _, ok := state.Dirs[dir]; !ok {
+					continue
+				}
+				dirState, err := idx.dirState(dir)
+				if err != nil {
+					return err
+				}
+				state.Dirs[dir] = dirState
+			}
+		}
+		return indexErr
+	}
+
+	idx.plan = newRunPlan()
+	idx.plan.transaction = true
+	defer func() {
+		idx.plan = nil
+	}()
+
+	// The state of a directory depends on its index file, which is only
+	// written by the commit
+	indexErr := idx.indexDirectories(directories, nil)
+	if indexErr != nil && !idx.keptGoing(indexErr) {
+		slog.Error("run failed, no index file was changed", "error", indexErr)
+		idx.report.abandoned(idx.plan.changes, indexErr)
+		return indexErr
+	}
+
+	if _, err := idx.removeObsolete(); err != nil {
+		idx.report.abandoned(idx.plan.changes, err)
+		return err
+	}
+
+	changes := idx.plan.changes
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	if err := idx.commitChanges(changes); err != nil {
+		idx.report.abandoned(changes, err)
+		return err
+	}
+
+	if state != nil {
+		failed := make(map[string]bool)
+		for _, dirErr := range DirErrors(indexErr) {
+			failed[dirErr.Dir] = true
+		}
+		for _, dir := range directories {
+			if failed[dir] {
+				delete(state.Dirs, dir)
+				continue
+			}
+			dirState, err := idx.dirState(dir)
+			if err != nil {
+				return err
+			}
+			state.Dirs[dir] = dirState
+		}
+	}
+	return indexErr
+}
+
+// commitChanges applies the changes of a transactional run. Every new
+// content is first written to the staging directory of the run; index files
+// are only touched once all of them are staged, and are then replaced by
+// renaming the staged files. If a change cannot be applied, the files
+// already changed are restored to their content before the run.
+func (idx *Indexator) commitChanges(changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	stagingDir := idx.stagingDir()
+	defer func() {
+		if err := os.RemoveAll(stagingDir); err != nil {
+			slog.Warn("failed to remove staging directory", "directory", stagingDir, "error", err)
+		}
+		// Only succeeds once no other run is staging
+		os.Remove(filepath.Dir(stagingDir))
+	}()
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Action == ActionRemove {
+			continue
+		}
+		staged := filepath.Join(stagingDir, filepath.FromSlash(change.Path))
+		if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+			return fmt.Errorf("failed to create staging directory for %s: %w", change.Path, err)
+		}
+		if err := os.WriteFile(staged, []byte(change.New), 0644); err != nil {
+			slog.Error("failed to stage index", "file", change.Path, "error", err)
+			return fmt.Errorf("failed to stage index file %s: %w", change.Path, err)
+		}
+		change.staged = staged
+	}
+
+	for i := range changes {
+		err := idx.applyChange(&changes[i])
+		if err == nil {
+			continue
+		}
+
+		// The failed change may have been backed up already, so it is
+		// restored too
+		slog.Error("failed to commit run, rolling back", "file", changes[i].Path, "error", err)
+		if rollbackErr := idx.rollback(changes[:i+1]); rollbackErr != nil {
+			return fmt.Errorf("%w; rollback failed: %w", err, rollbackErr)
+		}
+		return fmt.Errorf("%w; every index file was restored", err)
+	}
+	return nil
+}
+
+// rollback restores the files of the given changes to their content before
+// the run, in reverse order, and removes the files the run created. The
+// restores are not journaled, and the journal of the run is removed since
+// it no longer describes the vault.
+func (idx *Indexator) rollback(changes []Change) error {
+	journal := idx.journal
+	idx.journal = false
+	defer func() {
+		idx.journal = journal
+	}()
+
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := &changes[i]
+		if change.Action == ActionCreate {
+			if err := os.Remove(change.absPath); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", change.Path, err))
+			}
+			continue
+		}
+		if err := idx.writeFileAtomic(change.absPath, []byte(change.Old)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		slog.Info("Restored index", "file", change.absPath)
+	}
+
+	if journal {
+		journalPath := idx.journalPath(idx.currentRunID())
+		if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+			slog.Warn("failed to remove journal", "journal", journalPath, "error", err)
+		}
+	}
+	return errors.Join(errs...)
+}
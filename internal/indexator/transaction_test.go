@@ -0,0 +1,102 @@
+package indexator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexator_Transaction(t *testing.T) {
+	t.Run("failing directory changes nothing", func(t *testing.T) {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/a.md": "# A",
+			"B/b.md": "# B",
+		})
+		// Rendering fails for B only
+		templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+		writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+			"index.tmpl": `{{if eq .Name "B"}}{{.Missing}}{{end}}{{range .Entries}}{{.Link}}{{end}}`,
+		})
+
+		indexator := NewIndexatorWithOptions(tempDir, Options{TemplatePath: templatePath, Transaction: true, Journal: true})
+		if err := indexator.Start(); err == nil {
+			t.Fatal("Start() should fail when a directory cannot be indexed")
+		}
+		for _, path := range []string{"A/A.md", "index.md", StateDirName} {
+			if _, err := os.Stat(filepath.Join(tempDir, path)); err == nil {
+				t.Errorf("failed transactional run should not write %s", path)
+			}
+		}
+	})
+
+	t.Run("failed commit restores files", func(t *testing.T) {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/a.md": "# A",
+			"X":      "a file where a directory is expected",
+		})
+		if err := NewIndexator(tempDir).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		indexPath := filepath.Join(tempDir, "A/A.md")
+		original, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+
+		indexator := NewIndexatorWithOptions(tempDir, Options{Backup: true, Journal: true, Transaction: true})
+		indexator.beginRun()
+		changes := []Change{
+			{Action: ActionUpdate, Path: "A/A.md", Old: string(original), New: "rewritten", absPath: indexPath},
+			{Action: ActionCreate, Path: "X/X.md", New: "cannot be written", absPath: filepath.Join(tempDir, "X/X.md")},
+		}
+		if err := indexator.commitChanges(changes); err == nil {
+			t.Fatal("commitChanges() should fail when a file cannot be written")
+		}
+
+		content, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("rolled back index is missing: %v", err)
+		}
+		if string(content) != string(original) {
+			t.Errorf("commitChanges() left %q, want %q", content, original)
+		}
+		if runs, _ := indexator.JournalRuns(); len(runs) != 0 {
+			t.Errorf("rolled back run should not be journaled, got %v", runs)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, StateDirName, stagingDirName)); err == nil {
+			t.Error("commitChanges() should remove the staging directory")
+		}
+	})
+
+	t.Run("manifest matches committed files", func(t *testing.T) {
+		tempDir := t.TempDir()
+		writeTestFiles(t, tempDir, map[string]string{
+			"A/a.md":   "# A",
+			"A/B/b.md": "# B",
+		})
+		opts := Options{Incremental: true, Transaction: true}
+		if err := NewIndexatorWithOptions(tempDir, opts).Start(); err != nil {
+			t.Fatalf("Start() failed: %v", err)
+		}
+		for _, path := range []string{"A/B/B.md", "A/A.md", "index.md"} {
+			if _, err := os.Stat(filepath.Join(tempDir, path)); err != nil {
+				t.Errorf("transactional run should write %s", path)
+			}
+		}
+
+		indexator := NewIndexatorWithOptions(tempDir, opts)
+		directories, err := indexator.CollectDirectories()
+		if err != nil {
+			t.Fatalf("CollectDirectories() failed: %v", err)
+		}
+		_, changed, err := indexator.planIncremental(directories)
+		if err != nil {
+			t.Fatalf("planIncremental() failed: %v", err)
+		}
+		if changed == nil || len(changed) != 0 {
+			t.Errorf("second run should find nothing changed, got %v", changed)
+		}
+	})
+}
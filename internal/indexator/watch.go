@@ -0,0 +1,287 @@
+package indexator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long Watch waits after the last change before
+// updating indexes, so that a burst of changes produces a single rewrite
+const DefaultDebounce = 2 * time.Second
+
+// Watch indexes the vault and then keeps its indexes up to date until ctx is
+// cancelled. Changes are collected until the vault has been quiet for the
+// debounce interval; the affected directories and their ancestors are then
+// regenerated. Changes to settings files and templates trigger a full
+// incremental run.
+func (idx *Indexator) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("failed to create file watcher", "error", err)
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := idx.Start(); err != nil {
+		if !idx.keptGoing(err) {
+			return err
+		}
+		slog.Error("some directories could not be indexed", "error", err)
+	}
+	if err := idx.watchDirectories(watcher, "."); err != nil {
+		return err
+	}
+	idx.watchTemplates(watcher)
+	// Obsidian's excluded files live in .obsidian/app.json
+	if idx.obsidianSettingsWatched() {
+		if err := watcher.Add(filepath.Join(idx.vaultPath, ".obsidian")); err != nil {
+			slog.Debug("not watching Obsidian settings", "error", err)
+		}
+	}
+
+	debounce := idx.debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	pending := make(map[string]bool)
+	rescan := false
+
+	flush := func() {
+		if rescan {
+			slog.Info("Settings changed, rescanning vault")
+			if err := idx.Start(); err != nil {
+				slog.Error("failed to rescan vault", "error", err)
+			}
+			if err := idx.watchDirectories(watcher, "."); err != nil {
+				slog.Error("failed to watch vault", "error", err)
+			}
+			idx.watchTemplates(watcher)
+		} else if len(pending) > 0 {
+			if err := idx.reindex(pending); err != nil {
+				slog.Error("failed to update indexes", "error", err)
+			}
+		}
+		pending = make(map[string]bool)
+		rescan = false
+	}
+
+	slog.Info("Watching vault for changes", "vault", idx.vaultPath, "debounce", debounce)
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			slog.Info("Stopped watching vault", "vault", idx.vaultPath)
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("file watcher error", "error", err)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			relPath := idx.getRelativePath(event.Name)
+			switch {
+			case idx.isTemplateFile(event.Name):
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				rescan = true
+			case !filepath.IsLocal(relPath) && relPath != ".":
+				// Other files next to a template outside the vault
+				continue
+			case !idx.handleEvent(watcher, event, relPath, pending):
+				continue
+			case idx.isSettingsFile(relPath):
+				rescan = true
+			}
+			slog.Debug("vault changed", "path", relPath, "op", event.Op.String())
+			timer.Reset(debounce)
+
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// handleEvent records the directory affected by a file system event in
+// pending and reports whether the event is relevant
+func (idx *Indexator) handleEvent(watcher *fsnotify.Watcher, event fsnotify.Event, relPath string, pending map[string]bool) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if idx.isSettingsFile(relPath) {
+		return true
+	}
+
+	name := path.Base(relPath)
+	dir := path.Dir(relPath)
+	if isHiddenDir(dir) {
+		// Hidden directories, including the state directory, are never
+		// indexed
+		return false
+	}
+	if strings.HasSuffix(name, ".tmp") || strings.Contains(name, ".backup_") || idx.inBackupStore(relPath) {
+		return false
+	}
+	// Index files are rewritten by the Indexator itself; only their
+	// removal needs to be repaired
+	if name == idx.indexFileName(filepath.Join(idx.vaultPath, dir)) && !event.Has(fsnotify.Remove) {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !strings.HasPrefix(name, ".") {
+			if err := idx.watchDirectories(watcher, relPath); err != nil {
+				slog.Warn("failed to watch new directory", "path", relPath, "error", err)
+			}
+			directories, _ := idx.collectDirectories(relPath)
+			for _, d := range directories {
+				pending[d] = true
+			}
+		}
+	}
+
+	pending[dir] = true
+	return true
+}
+
+// isSettingsFile reports whether a vault-relative path is a file that
+// changes how the whole vault, or a subtree of it, is indexed
+func (idx *Indexator) isSettingsFile(relPath string) bool {
+	name := path.Base(relPath)
+	switch {
+	case isOverrideFile(name):
+		return true
+	case idx.respectGitignore && name == gitignoreFileName:
+		return true
+	case relPath == ".obsidian/app.json":
+		return idx.obsidianSettingsWatched()
+	}
+	return false
+}
+
+// isHiddenDir reports whether a vault-relative directory is, or is inside, a
+// hidden directory
+func isHiddenDir(relDir string) bool {
+	for _, part := range strings.Split(cleanRelPath(relDir), "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// isTemplateFile reports whether a file is a template used by the last run
+func (idx *Indexator) isTemplateFile(filePath string) bool {
+	for _, templatePath := range idx.templateFiles() {
+		if filePath == templatePath {
+			return true
+		}
+	}
+	return false
+}
+
+// watchTemplates adds the directories of the templates used by the last run
+// to the watcher. Directories are watched rather than the files, since
+// editors often replace a file instead of writing to it.
+func (idx *Indexator) watchTemplates(watcher *fsnotify.Watcher) {
+	for _, templatePath := range idx.templateFiles() {
+		if err := watcher.Add(filepath.Dir(templatePath)); err != nil {
+			slog.Warn("failed to watch template", "file", templatePath, "error", err)
+		}
+	}
+}
+
+func (idx *Indexator) obsidianSettingsWatched() bool {
+	return !idx.ignoreObsidianExcludes
+}
+
+// watchDirectories adds the directories to index below the vault-relative
+// directory root to the watcher
+func (idx *Indexator) watchDirectories(watcher *fsnotify.Watcher, root string) error {
+	directories, err := idx.collectDirectories(root)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range directories {
+		dirPath := filepath.Join(idx.vaultPath, dir)
+		if err := watcher.Add(dirPath); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			slog.Error("failed to watch directory", "directory", dir, "error", err)
+			return fmt.Errorf("failed to watch directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// reindex regenerates the given vault-relative directories and their
+// ancestors, deepest first, and records their new state
+func (idx *Indexator) reindex(dirs map[string]bool) error {
+	targets := make(map[string]bool)
+	for dir := range dirs {
+		for d := cleanRelPath(dir); !targets[d]; d = path.Dir(d) {
+			targets[d] = true
+			if d == "." {
+				break
+			}
+		}
+	}
+
+	directories := make([]string, 0, len(targets))
+	for dir := range targets {
+		directories = append(directories, dir)
+	}
+	sort.Strings(directories)
+	sort.SliceStable(directories, func(i, j int) bool {
+		return pathDepth(directories[i]) > pathDepth(directories[j])
+	})
+
+	existing := directories[:0]
+	for _, dir := range directories {
+		if _, err := os.Stat(filepath.Join(idx.vaultPath, dir)); os.IsNotExist(err) {
+			if idx.state != nil {
+				delete(idx.state.Dirs, dir)
+			}
+			continue
+		}
+		existing = append(existing, dir)
+	}
+
+	slog.Info("Updating indexes", "directories", len(existing))
+	idx.beginIndexRun()
+	defer idx.report.end()
+	indexErr := idx.withFailedDirs(idx.runDirectories(existing, idx.state))
+	if indexErr != nil && !idx.keptGoing(indexErr) {
+		return indexErr
+	}
+	idx.endRun()
+
+	if idx.state != nil && !idx.dryRun {
+		if err := idx.saveManifest(idx.state); err != nil {
+			return err
+		}
+	}
+	return indexErr
+}
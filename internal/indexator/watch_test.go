@@ -0,0 +1,108 @@
+package indexator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexator_Watch(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"Notes/one.md": "# One",
+	})
+
+	// The template lives outside the vault
+	templatePath := filepath.Join(t.TempDir(), "index.tmpl")
+	writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+		"index.tmpl": "{{range .Entries}}{{.Link}}\n{{end}}",
+	})
+
+	indexator := NewIndexatorWithOptions(tempDir, Options{Update: true, TemplatePath: templatePath, Debounce: 50 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- indexator.Watch(ctx)
+	}()
+
+	// waitFor polls an index file until it contains want
+	waitFor := func(t *testing.T, indexPath, want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			content, err := os.ReadFile(filepath.Join(tempDir, indexPath))
+			if err == nil && strings.Contains(string(content), want) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("%s does not contain %q", indexPath, want)
+	}
+
+	waitFor(t, "Notes/Notes.md", "[[Notes/one.md]]")
+
+	writeTestFiles(t, tempDir, map[string]string{"Notes/two.md": "# Two"})
+	waitFor(t, "Notes/Notes.md", "[[Notes/two.md]]")
+
+	writeTestFiles(t, tempDir, map[string]string{"Notes/Sub/three.md": "# Three"})
+	waitFor(t, "Notes/Sub/Sub.md", "[[Notes/Sub/three.md]]")
+	waitFor(t, "Notes/Notes.md", "[[Notes/Sub/Sub.md]]")
+
+	if err := os.Remove(filepath.Join(tempDir, "Notes/one.md")); err != nil {
+		t.Fatalf("Failed to remove note: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		content, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+		if err == nil && !strings.Contains(string(content), "one.md") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Removed note is still listed: %q", string(content))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	writeTestFiles(t, filepath.Dir(templatePath), map[string]string{
+		"index.tmpl": "Generated {{.Generated.UnixNano}}\n{{range .Entries}}{{.Link}}\n{{end}}",
+	})
+	waitFor(t, "Notes/Notes.md", "Generated ")
+	first, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	// Every update is a new run with its own generation time
+	writeTestFiles(t, tempDir, map[string]string{"Notes/four.md": "# Four"})
+	waitFor(t, "Notes/Notes.md", "[[Notes/four.md]]")
+	second, err := os.ReadFile(filepath.Join(tempDir, "Notes/Notes.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if firstLine(string(first)) == firstLine(string(second)) {
+		t.Errorf("update kept the generation time of the previous run: %q", firstLine(string(second)))
+	}
+	if report := indexator.Report(); len(report.Dirs) != 2 {
+		t.Errorf("report of the last update = %+v, want Notes and the vault root only", report.Dirs)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not stop after cancellation")
+	}
+}
+
+// firstLine returns the first line of the text of an index after its
+// generated marker
+func firstLine(content string) string {
+	lines := strings.Split(strings.TrimPrefix(content, generatedMarker+"\n"), "\n")
+	return lines[0]
+}